@@ -12,6 +12,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 	"unsafe"
 
 	"github.com/lucasmelin/raytracer/internal/display"
@@ -26,6 +27,8 @@ const (
 	SIMPLE_LIGHT
 	JUPITER
 	PERLIN_SPHERES
+	QUADRICS
+	STAGE
 )
 
 // raysPerPixelList is used to define the number of rays per-pixel, per phase.
@@ -57,6 +60,16 @@ type options struct {
 	Seed         int64
 	CPU          int
 	Scene        int
+	Spectral     bool
+	Environment  string
+	EnvRotation  float64
+	EnvIntensity float64
+	Sky          bool
+	Latitude     float64
+	Longitude    float64
+	Time         string
+	Turbidity    float64
+	GroundAlbedo float64
 }
 
 // disp will update the display with the pixels as they get rendered by each goroutine.
@@ -133,6 +146,16 @@ func main() {
 	flag.Var(&options.RaysPerPixel, "r", "comma separated list of rays-per-pixel")
 	flag.StringVar(&options.Output, "o", "image.png", "path to output file")
 	flag.IntVar(&options.Scene, "scene", FINAL_WORLD, "scene to render")
+	flag.BoolVar(&options.Spectral, "spectral", false, "trace wavelengths of light instead of RGB channels")
+	flag.StringVar(&options.Environment, "env", "", "path to an equirectangular .hdr or .exr image lighting the scene")
+	flag.Float64Var(&options.EnvRotation, "env-rotation", 0, "rotation of the environment around the vertical axis in degrees")
+	flag.Float64Var(&options.EnvIntensity, "env-intensity", 1, "intensity of the environment")
+	flag.BoolVar(&options.Sky, "sky", false, "light the scene with the sun and the daylight sky")
+	flag.Float64Var(&options.Latitude, "latitude", 45, "latitude of the scene in degrees, positive towards the north")
+	flag.Float64Var(&options.Longitude, "longitude", 0, "longitude of the scene in degrees, positive towards the east")
+	flag.StringVar(&options.Time, "time", "2024-06-21T15:00:00Z", "time of day lighting the scene, in RFC 3339 format")
+	flag.Float64Var(&options.Turbidity, "turbidity", 3, "haze of the sky, from 2 for a clear sky to 10 for a hazy one")
+	flag.Float64Var(&options.GroundAlbedo, "ground-albedo", 0.3, "share of the light reflected by the ground below the horizon")
 
 	flag.Parse()
 
@@ -178,6 +201,8 @@ func main() {
 	var camera cameraSensor
 	var bvh *display.BVH
 	var bg backgrounder
+	var medium display.Medium
+	var lights []display.LightSource
 	switch options.Scene {
 	case FINAL_WORLD:
 		camera, bvh = buildFinalWorld(options.Width, options.Height)
@@ -185,6 +210,8 @@ func main() {
 	case WEEK_ONE:
 		camera, bvh = buildWeekOneWorld(options.Width, options.Height)
 		bg = BlackBackdrop{}
+		// A faint mist filling the whole scene.
+		medium = display.NewScatteringMedium(display.Black, display.White.Scale(0.0001), 0)
 	case CORNELL_SMOKE:
 		camera, bvh = cornellSmoke(options.Width, options.Height)
 		bg = BlackBackdrop{}
@@ -200,18 +227,50 @@ func main() {
 	case PERLIN_SPHERES:
 		camera, bvh = buildTwoPerlinSpheresWorld(options.Width, options.Height)
 		bg = BlueSky{}
+	case QUADRICS:
+		camera, bvh = quadrics(options.Width, options.Height)
+		bg = BlueSky{}
+	case STAGE:
+		camera, bvh, lights = stage(options.Width, options.Height)
+		bg = BlackBackdrop{}
 	default:
 		fmt.Printf("unknown scene %d, defaulting to Final World\n", options.Scene)
 		camera, bvh = buildFinalWorld(options.Width, options.Height)
 		bg = BlueSky{}
 	}
 
+	if options.Environment != "" {
+		img, err := display.LoadHDR(options.Environment)
+		if err != nil {
+			newErr := fmt.Errorf("could not load environment: %w", err)
+			panic(newErr)
+		}
+		env := display.NewEnvironment(img, options.EnvRotation, options.EnvIntensity)
+		bg = EnvironmentMap{env}
+		lights = append(lights, env)
+	}
+
+	if options.Sky {
+		at, err := time.Parse(time.RFC3339, options.Time)
+		if err != nil {
+			newErr := fmt.Errorf("could not parse time: %w", err)
+			panic(newErr)
+		}
+		sun := display.SunDirection(options.Latitude, options.Longitude, at)
+		sky := display.NewSky(sun, options.Turbidity, options.GroundAlbedo)
+		bg = PhysicalSky{sky}
+		lights = append(lights, sky)
+	}
+
 	scene := &scene{
 		width:        options.Width,
 		height:       options.Height,
 		raysPerPixel: options.RaysPerPixel,
 		camera:       camera,
 		hitBoxer:     bvh,
+		medium:       medium,
+		lights:       lights,
+		spectral:     options.Spectral,
 	}
 	pixels, completed := scene.render(options.CPU, bg)
 
@@ -25,6 +25,9 @@ type scene struct {
 	raysPerPixel  []int // array index represents the renderPixel pass
 	camera        cameraSensor
 	hitBoxer      display.HitBoxer
+	medium        display.Medium        // the medium filling the scene up to its bounding box, such as fog, or nil
+	lights        []display.LightSource // the lights sampled directly wherever rays scatter
+	spectral      bool                  // whether rays carry wavelengths instead of RGB channels
 }
 
 // pixel represents the pixel to be processed.
@@ -63,7 +66,12 @@ func (scene *scene) renderPixel(rnd geometry.Rnd, pixel *pixel, raysPerPixel int
 		u := (float64(pixel.x) + rnd.Float64()) / float64(scene.width)
 		v := (float64(pixel.y) + rnd.Float64()) / float64(scene.height)
 		r := scene.camera.ray(rnd, u, v)
-		c = c.Add(rayColor(r, scene.hitBoxer, 0, bg))
+		if scene.spectral {
+			r.Wavelengths = display.SampleWavelengths(rnd.Float64())
+			c = c.Add(display.SpectralToRGB(rayColor(r, scene.hitBoxer, 0, bg, scene.lights, display.NewMediumStack(scene.medium), 0), r.Wavelengths))
+			continue
+		}
+		c = c.Add(rayColor(r, scene.hitBoxer, 0, bg, scene.lights, display.NewMediumStack(scene.medium), 0))
 	}
 
 	pixel.color = c
@@ -177,25 +185,130 @@ func (scene *scene) render(parallelCount int, bg backgrounder) (pixels, chan str
 }
 
 // rayColor computes the color of the ray and scatters more rays according to the properties of the hittable.
-func rayColor(r *geometry.Ray, hb display.HitBoxer, depth int, bg backgrounder) display.Color {
-	if hit, hr := hb.Hit(r, bias, math.MaxFloat64); hit {
-		// If we've exceeded the ray bounce limit, no more light is gathered.
-		if depth >= renderDepth {
-			return display.Black
+//
+// The media hold the matter the ray travels through. Light reaching the origin of the ray is
+// attenuated by the current medium over the distance it travelled.
+//
+// The light sources are gathered directly wherever the ray scatters off a material that can evaluate
+// its scattering, as rays never hit them by chance. The pdf is the density with which such a material
+// scattered the ray in its direction, or 0, for weighing the light the ray reaches against the light
+// sampled directly.
+//
+// When the ray carries wavelengths, every color is sampled at those wavelengths.
+func rayColor(r *geometry.Ray, hb display.HitBoxer, depth int, bg backgrounder, lights []display.LightSource, media display.MediumStack, pdf float64) display.Color {
+	hit, hr := hb.Hit(r, bias, math.MaxFloat64)
+	medium := media.Current()
+	transmittance, inScattered := display.White, display.Black
+	if p, ok := medium.(display.Participating); ok {
+		// The medium may scatter the ray before it reaches the surface, and glow along the way.
+		// Rays missing every surface leave the medium at the edge of the scene, out to the background.
+		dMax := 0.0
+		if hit {
+			dMax = hr.T()
+		} else if inside, _, exit := hb.Box(0, 1).Clip(r, 0, math.MaxFloat64); inside {
+			dMax = exit
+		}
+		var event *display.HitRecord
+		event, transmittance, inScattered = p.Interact(r, dMax)
+		if event != nil {
+			hit, hr = true, event
+		}
+	} else if medium != nil && hit {
+		transmittance = display.Spectral(medium.Transmittance(hr.T()), r)
+	}
+	if !hit {
+		light := display.Spectral(bg.background(r), r)
+		if l, ok := bg.(lightBackground); ok && pdf > 0 {
+			light = light.Scale(powerHeuristic(pdf, l.PDF(r.Direction)))
+		}
+		return inScattered.Add(transmittance.Mul(light))
+	}
+	if _, boundary := hr.Material.(display.MediumBoundary); boundary || !media.Resolve(r, hr) {
+		// The surface only bounds a medium, or is hidden within a medium of higher priority, so the ray goes
+		// straight through it.
+		crossed, next := media.Cross(r, hr)
+		return inScattered.Add(transmittance.Mul(rayColor(crossed, hb, depth, bg, lights, next, pdf)))
+	}
+	// If we've exceeded the ray bounce limit, no more light is gathered.
+	if depth >= renderDepth {
+		return inScattered
+	}
+	emitted := display.Spectral(hr.Material.Emit(r, hr), r)
+	direct := directLight(r, hr, hb, lights, media)
+	if wasScattered, attenuation, scattered := hr.Material.Scatter(r, hr); wasScattered {
+		next := media.Next(r, hr, scattered)
+		weight := display.CarryWavelengths(r, *attenuation, scattered)
+		scatteredPDF := 0.0
+		if material, ok := hr.Material.(display.Evaluator); ok && scattered.Spread {
+			_, scatteredPDF = material.Evaluate(r, hr, scattered.Direction)
+		}
+		indirect := weight.Mul(rayColor(scattered, hb, depth+1, bg, lights, next, scatteredPDF))
+		return inScattered.Add(transmittance.Mul(emitted.Add(direct).Add(indirect)))
+	}
+	return inScattered.Add(transmittance.Mul(emitted.Add(direct)))
+}
+
+// directLight returns the light arriving from the light sources at the surface described by hr, and scattered
+// towards the origin of the ray r. Materials that cannot evaluate their scattering gather no direct light.
+//
+// Light from lights sampled over a spread of directions is weighed against the chance of the material
+// scattering rays towards it, with the power heuristic.
+func directLight(r *geometry.Ray, hr *display.HitRecord, hb display.HitBoxer, lights []display.LightSource, media display.MediumStack) display.Color {
+	material, ok := hr.Material.(display.Evaluator)
+	if !ok {
+		return display.Black
+	}
+	direct := display.Black
+	for _, light := range lights {
+		sample := light.Sample(hr.P(), r.Rnd)
+		if sample.Light == display.Black {
+			continue
 		}
-		if wasScattered, attenuation, scattered := hr.Material.Scatter(r, hr); wasScattered {
-			indirect := attenuation.Mul(rayColor(scattered, hb, depth+1, bg))
-			return hr.Material.Emit(hr).Add(indirect)
+		scattered, pdf := material.Evaluate(r, hr, sample.Direction)
+		if scattered == display.Black {
+			continue
 		}
-		return hr.Material.Emit(hr)
+		shadow := geometry.NewRay(hr.P(), sample.Direction, r.Time, r.Rnd)
+		shadow.Wavelengths = r.Wavelengths
+		visibility := media.Next(r, hr, shadow).Visibility(hb, shadow, sample.Distance)
+		arriving := display.Spectral(sample.Light, r).Mul(visibility)
+		if sample.PDF > 0 {
+			arriving = arriving.Scale(powerHeuristic(sample.PDF, pdf))
+		}
+		direct = direct.Add(display.Spectral(scattered, r).Mul(arriving))
 	}
-	return bg.background(r)
+	return direct
+}
+
+// powerHeuristic returns the weight of a sample taken with the density pdf, against another sampling
+// technique with the density other, from "Optimally Combining Sampling Techniques for Monte Carlo Rendering"
+// by Veach and Guibas.
+func powerHeuristic(pdf float64, other float64) float64 {
+	return pdf * pdf / (pdf*pdf + other*other)
 }
 
 type backgrounder interface {
 	background(r *geometry.Ray) display.Color
 }
 
+// lightBackground is implemented by backgrounders that are also among the lights of the scene, whose light
+// reached by scattered rays is weighed against the light sampled directly.
+type lightBackground interface {
+	backgrounder
+	// PDF returns the density with which the light is sampled in the direction dir.
+	PDF(dir geometry.Unit) float64
+}
+
+// EnvironmentMap is a backgrounder showing an HDR environment, which is also sampled among the lights.
+type EnvironmentMap struct {
+	*display.Environment
+}
+
+// PhysicalSky is a backgrounder showing the daylight sky and the sun, which are also sampled among the lights.
+type PhysicalSky struct {
+	*display.Sky
+}
+
 type BlueSky struct {
 }
 
@@ -222,3 +335,11 @@ func (b FlatSky) background(r *geometry.Ray) display.Color {
 func (b BlackBackdrop) background(r *geometry.Ray) display.Color {
 	return display.Black
 }
+
+func (e EnvironmentMap) background(r *geometry.Ray) display.Color {
+	return e.Radiance(r.Direction)
+}
+
+func (s PhysicalSky) background(r *geometry.Ray) display.Color {
+	return s.Radiance(r.Direction)
+}
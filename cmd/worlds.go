@@ -34,8 +34,6 @@ func buildWeekOneWorld(width int, height int) (cameraSensor, *display.BVH) {
 	boundary := display.NewSphere(geometry.NewVec(360, 150, 145), 70, display.NewDielectric(1.5))
 	world.Add(boundary)
 	world.Add(display.NewVolume(boundary, 0.2, display.NewIsotropic(display.NewSolid(display.NewColor(0.2, 0.4, 0.9)), rnd)))
-	boundary = display.NewSphere(geometry.NewVec(0, 0, 0), 5000, display.NewDielectric(1.5))
-	world.Add(display.NewVolume(boundary, 0.0001, display.NewIsotropic(display.NewSolid(display.NewColor(1, 1, 1)), rnd)))
 	f, err := os.Open("assets/earthtwo.jpeg")
 	if err != nil {
 		panic(err)
@@ -381,3 +379,105 @@ func buildFinalWorld(width, height int) (cameraSensor, *display.BVH) {
 
 	return camera, display.NewBVH(0, 0, 1, world.Hittables...)
 }
+
+// quadrics is a row of cylinders, cones, tori and other analytic shapes on a checkered floor.
+func quadrics(width, height int) (cameraSensor, *display.BVH) {
+	world := display.List{}
+	checker := display.NewChecker(
+		3,
+		display.NewSolid(display.NewColor(0.2, 0.3, 0.1)),
+		display.NewSolid(display.NewColor(0.9, 0.9, 0.9)),
+	)
+	f, err := os.Open("assets/mars.jpeg")
+	if err != nil {
+		panic(err)
+	}
+	mars, err := display.NewImage(f)
+	if err != nil {
+		panic(err)
+	}
+
+	pipe := display.NewCylinder(geometry.NewVec(-4, 0, 0), 0.8, 2, display.NewLambertian(mars))
+	pipe.Sweep = 270
+	paint := display.NewPrincipled(display.NewSolid(display.NewColor(0.7, 0.3, 0.1)), 0, 0.5)
+	paint.Clearcoat = display.NewGray(1)
+	ring := display.NewTorus(geometry.NewVec(0, 0, 0), 0.8, 0.3, display.NewPresetConductor("gold", 0.3))
+
+	world.Hittables = append(world.Hittables,
+		display.NewSphere(geometry.NewVec(0, -1000, 0), 1000, display.NewLambertian(checker)),
+		pipe,
+		display.NewCone(geometry.NewVec(-2, 0, 0), 0.8, 2, paint),
+		display.NewTranslate(display.NewRotateY(ring, 30), geometry.NewVec(0, 0.3, 0)),
+		display.NewParaboloid(geometry.NewVec(2, 0, 0), 0.8, 1.6, display.NewAbsorbingDielectric(1.5, display.NewAbsorbingColor(display.NewColor(0.3, 0.8, 0.5), 1))),
+		display.NewHyperboloid(geometry.NewVec(4, 0, 0), 0.8, 0.4, 2, display.NewLambertian(display.NewSolid(display.NewColor(0.2, 0.4, 0.8)))),
+		display.NewDisk(geometry.NewVec(0, 0.001, 3), 1, display.NewLambertian(mars)),
+	)
+
+	lookAt := geometry.NewVec(0, 1, 0)
+	lookFrom := geometry.NewVec(0, 4, 14)
+	aperture := 0.0
+	distToFocus := 14.0
+	camera := newCamera(
+		lookFrom,
+		lookAt,
+		geometry.NewVec(0, 1.0, 0),
+		40,
+		float64(width)/float64(height),
+		aperture,
+		distToFocus,
+	)
+	return camera, display.NewBVH(0, 0, 1, world.Hittables...)
+}
+
+// stage is a dark stage lit by a downlight, a spotlight projecting the moon and a low sun through a haze.
+func stage(width, height int) (cameraSensor, *display.BVH, []display.LightSource) {
+	f, err := os.Open("assets/moon.jpeg")
+	if err != nil {
+		panic(err)
+	}
+	moon, err := display.NewImage(f)
+	if err != nil {
+		panic(err)
+	}
+	downlight, err := display.LoadIES("assets/downlight.ies")
+	if err != nil {
+		panic(err)
+	}
+
+	floor := display.NewLambertian(display.NewSolid(display.NewColor(0.6, 0.6, 0.6)))
+	haze := display.NewScatteringMedium(display.Black, display.White.Scale(0.5), 0.6)
+	world := display.List{}
+	world.Hittables = append(world.Hittables,
+		display.NewSphere(geometry.NewVec(0, -1000, 0), 1000, floor),
+		display.NewRectangle(geometry.NewVec(-6, 0, -3), geometry.NewVec(6, 6, -3), floor),
+		display.NewBlock(geometry.NewVec(-5, 0, -2.5), geometry.NewVec(-2, 3, -1.5), display.NewMediumBoundary(haze)),
+		display.NewSphere(geometry.NewVec(-1.8, 0.8, 0), 0.8, display.NewPrincipled(display.NewSolid(display.NewColor(0.8, 0.1, 0.1)), 0, 0.3)),
+		display.NewSphere(geometry.NewVec(0, 0.8, 0), 0.8, display.NewPresetConductor("gold", 0.3)),
+		display.NewSphere(geometry.NewVec(1.8, 0.8, 0), 0.8, display.NewOrenNayar(display.NewSolid(display.NewColor(0.2, 0.5, 0.8)), 30)),
+	)
+
+	spot := display.NewSpotLight(geometry.NewVec(3, 5, 3), geometry.NewVec(-0.5, -0.5, -1), display.NewColor(1, 0.9, 0.7), 150, 20, 4)
+	spot.Gobo = moon
+	bulb := display.NewPointLight(geometry.NewVec(-2, 3, 2), display.NewColor(0.6, 0.7, 1), display.Lumens(200000))
+	bulb.Profile = downlight
+	lights := []display.LightSource{
+		bulb,
+		spot,
+		display.NewDirectionalLight(geometry.NewVec(1, -0.6, -0.5), display.NewColor(1, 0.8, 0.6), 0.4),
+	}
+
+	lookAt := geometry.NewVec(0, 1, 0)
+	lookFrom := geometry.NewVec(0, 3, 9)
+	aperture := 0.0
+	distToFocus := 9.0
+	camera := newCamera(
+		lookFrom,
+		lookAt,
+		geometry.NewVec(0, 1.0, 0),
+		40,
+		float64(width)/float64(height),
+		aperture,
+		distToFocus,
+	)
+	return camera, display.NewBVH(0, 0, 1, world.Hittables...), lights
+}
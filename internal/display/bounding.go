@@ -95,3 +95,30 @@ func (ab *AABB) Corners() []geometry.Vec {
 func (ab *AABB) Extend(v geometry.Vec) *AABB {
 	return NewAABB(ab.Min.Min(v), ab.Max.Max(v))
 }
+
+// Clip returns whether the given ray hits the bounding box, along with the range of distances
+// between dMin and dMax for which the ray is inside the box.
+func (ab *AABB) Clip(ray *geometry.Ray, dMin float64, dMax float64) (bool, float64, float64) {
+	origin := [3]float64{ray.Origin.X, ray.Origin.Y, ray.Origin.Z}
+	direction := [3]float64{ray.Direction.X, ray.Direction.Y, ray.Direction.Z}
+	min := [3]float64{ab.Min.X, ab.Min.Y, ab.Min.Z}
+	max := [3]float64{ab.Max.X, ab.Max.Y, ab.Max.Z}
+	for a := 0; a < 3; a++ {
+		invD := 1 / direction[a]
+		d0 := (min[a] - origin[a]) * invD
+		d1 := (max[a] - origin[a]) * invD
+		if invD < 0 {
+			d0, d1 = d1, d0
+		}
+		if d0 > dMin {
+			dMin = d0
+		}
+		if d1 < dMax {
+			dMax = d1
+		}
+		if dMax <= dMin {
+			return false, 0, 0
+		}
+	}
+	return true, dMin, dMax
+}
@@ -53,6 +53,11 @@ func (c Color) Add(c2 Color) Color {
 	return Color{Vec: c.Vec.Add(c2.Vec)}
 }
 
+// Luminance returns the brightness of the color as perceived by the eye, using the Rec. 709 weights.
+func (c Color) Luminance() float64 {
+	return 0.2126*c.X + 0.7152*c.Y + 0.0722*c.Z
+}
+
 // PixelValue converts a Color into a pixel value.
 func (c Color) PixelValue() uint32 {
 	r := uint32(math.Min(255.0, c.X*255.99))
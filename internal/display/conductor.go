@@ -0,0 +1,107 @@
+package display
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+// ComplexIOR represents the complex index of refraction Eta + iK of a conductor for each channel.
+// K is the extinction coefficient, which measures how quickly light is absorbed inside the metal.
+type ComplexIOR struct {
+	Eta Color
+	K   Color
+}
+
+// Metals holds measured indices of refraction of common metals, sampled at 650, 550 and 450nm
+// for the red, green and blue channels.
+var Metals = map[string]ComplexIOR{
+	"aluminium": {Eta: NewColor(1.657, 0.880, 0.521), K: NewColor(9.224, 6.270, 4.837)},
+	"brass":     {Eta: NewColor(0.444, 0.527, 1.094), K: NewColor(3.695, 2.765, 1.829)},
+	"chromium":  {Eta: NewColor(3.105, 3.183, 2.320), K: NewColor(3.321, 3.330, 3.089)},
+	"copper":    {Eta: NewColor(0.200, 0.924, 1.102), K: NewColor(3.912, 2.452, 2.142)},
+	"gold":      {Eta: NewColor(0.143, 0.374, 1.442), K: NewColor(3.983, 2.386, 1.603)},
+	"iron":      {Eta: NewColor(2.912, 2.950, 2.585), K: NewColor(3.077, 2.931, 2.768)},
+	"nickel":    {Eta: NewColor(1.990, 1.820, 1.630), K: NewColor(3.730, 3.350, 2.920)},
+	"platinum":  {Eta: NewColor(2.375, 2.085, 1.845), K: NewColor(4.265, 3.715, 3.137)},
+	"silver":    {Eta: NewColor(0.155, 0.117, 0.138), K: NewColor(4.828, 3.122, 2.147)},
+	"titanium":  {Eta: NewColor(2.160, 1.870, 1.660), K: NewColor(2.930, 2.610, 2.400)},
+}
+
+// Conductor represents a metal whose roughness follows the GGX microfacet distribution.
+//
+// Unlike Metal, light is reflected according to the Fresnel equations of the metal's complex
+// index of refraction and rough surfaces never scatter light below the surface. The roughness
+// can differ along the two tangents of the surface to model brushed metal, with RoughnessX
+// following the direction in which the u coordinate of the surface increases.
+type Conductor struct {
+	IOR        ComplexIOR
+	RoughnessX float64
+	RoughnessY float64
+	nonEmitter
+}
+
+// NewConductor creates a new Conductor with a given index of refraction and roughness.
+func NewConductor(ior ComplexIOR, roughness float64) Conductor {
+	return Conductor{IOR: ior, RoughnessX: roughness, RoughnessY: roughness}
+}
+
+// NewAnisotropicConductor creates a new Conductor with a different roughness along each tangent of the surface.
+func NewAnisotropicConductor(ior ComplexIOR, roughnessX float64, roughnessY float64) Conductor {
+	return Conductor{IOR: ior, RoughnessX: roughnessX, RoughnessY: roughnessY}
+}
+
+// NewPresetConductor creates a new Conductor from one of the Metals.
+func NewPresetConductor(name string, roughness float64) Conductor {
+	ior, ok := Metals[name]
+	if !ok {
+		panic(fmt.Sprintf("No preset for metal %q", name))
+	}
+	return NewConductor(ior, roughness)
+}
+
+// NewColoredConductor creates a new Conductor that reflects the given color when viewed head on.
+//
+// The index of refraction is derived from the color with the artist-friendly mapping from
+// "Artist Friendly Metallic Fresnel" by Ole Gulbrandsen, using the color as the edge tint too.
+// This makes it a drop-in replacement for a Metal of the same color.
+func NewColoredConductor(albedo Color, roughness float64) Conductor {
+	eta := func(r float64) float64 {
+		sqrt := math.Sqrt(r)
+		return (1-r)/(1+r)*r + (1+sqrt)/(1-sqrt)*(1-r)
+	}
+	k := func(r float64, n float64) float64 {
+		return math.Sqrt(math.Max(0, (r*(n+1)*(n+1)-(n-1)*(n-1))/(1-r)))
+	}
+	r := albedo.Vec.Min(geometry.NewVec(0.99, 0.99, 0.99)).Max(geometry.Vec{})
+	n := NewColor(eta(r.X), eta(r.Y), eta(r.Z))
+	return NewConductor(ComplexIOR{Eta: n, K: NewColor(k(r.X, n.X), k(r.Y, n.Y), k(r.Z, n.Z))}, roughness)
+}
+
+// Scatter reflects light rays off a microfacet sampled from the distribution of normals visible from the ray.
+func (c Conductor) Scatter(r *geometry.Ray, rec *HitRecord) (bool, *Color, *geometry.Ray) {
+	basis, wo, _ := localFrame(r, rec)
+	dist := newGGX(c.RoughnessX, c.RoughnessY)
+	wm := dist.sampleNormal(wo, r.Rnd)
+	wi := reflect(wo, wm)
+	if wi.Z <= 0 {
+		// The reflected ray is blocked by the surface itself.
+		return false, &Color{}, &geometry.Ray{}
+	}
+	attenuation := fresnelConductorColor(wo.Dot(wm), c.IOR.Eta, c.IOR.K).Scale(dist.weight(wo, wi))
+	scattered := scatterLocal(r, rec, basis, wi)
+	scattered.Spread = !dist.smooth()
+	return true, &attenuation, scattered
+}
+
+// Evaluate returns the light reflected from wi by the microfacets facing halfway between wi and the viewer.
+// Smooth conductors only reflect light from the mirror direction, and return nothing.
+func (c Conductor) Evaluate(r *geometry.Ray, rec *HitRecord, wi geometry.Unit) (Color, float64) {
+	basis, wo, _ := localFrame(r, rec)
+	wm, value, pdf, ok := newGGX(c.RoughnessX, c.RoughnessY).reflection(wo, basis.Local(wi.Vec))
+	if !ok {
+		return Black, 0
+	}
+	return fresnelConductorColor(wo.Dot(wm), c.IOR.Eta, c.IOR.K).Scale(value), pdf
+}
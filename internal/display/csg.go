@@ -0,0 +1,121 @@
+package display
+
+import (
+	"math"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+// Operation represents a boolean operation used to combine two solids.
+type Operation int
+
+const (
+	// Union keeps every point inside either solid.
+	Union Operation = iota
+	// Intersection keeps the points inside both solids.
+	Intersection
+	// Difference keeps the points inside the first solid but outside the second.
+	Difference
+)
+
+// inside returns whether a point is inside the combined solid, given whether it is inside each of the two solids.
+func (op Operation) inside(inLeft bool, inRight bool) bool {
+	switch op {
+	case Union:
+		return inLeft || inRight
+	case Intersection:
+		return inLeft && inRight
+	default:
+		return inLeft && !inRight
+	}
+}
+
+// CSG represents a solid built by combining two closed HitBoxers with a boolean Operation.
+//
+// Both children must be closed surfaces with normals pointing outwards, such as spheres, blocks or capped
+// quadrics, so that every intersection can be classified as entering or leaving the solid. The surfaces
+// carved out by the Right child of a Difference keep the material of the Right child.
+type CSG struct {
+	Left  HitBoxer
+	Right HitBoxer
+	Op    Operation
+}
+
+// NewUnion returns a new CSG that combines both solids.
+func NewUnion(left HitBoxer, right HitBoxer) *CSG {
+	return &CSG{Left: left, Right: right, Op: Union}
+}
+
+// NewIntersection returns a new CSG that keeps the volume shared by both solids.
+func NewIntersection(left HitBoxer, right HitBoxer) *CSG {
+	return &CSG{Left: left, Right: right, Op: Intersection}
+}
+
+// NewDifference returns a new CSG that removes the right solid from the left solid.
+func NewDifference(left HitBoxer, right HitBoxer) *CSG {
+	return &CSG{Left: left, Right: right, Op: Difference}
+}
+
+// Hit finds the first intersection between a ray and the surface of the combined solid.
+//
+// The ray is followed from infinitely far behind its origin, where it is outside of both solids,
+// through every boundary of either child in order. Each boundary toggles whether the ray is inside
+// that child; a boundary is part of the combined surface when it also toggles whether the ray is
+// inside the combined solid.
+func (c *CSG) Hit(r *geometry.Ray, tMin float64, tMax float64) (bool, *HitRecord) {
+	inLeft, inRight := false, false
+	inside := false
+
+	start := -math.MaxFloat64
+	leftHit, left := c.Left.Hit(r, start, math.MaxFloat64)
+	rightHit, right := c.Right.Hit(r, start, math.MaxFloat64)
+	for leftHit || rightHit {
+		fromLeft := leftHit && (!rightHit || left.t <= right.t)
+		hr := right
+		if fromLeft {
+			hr = left
+		}
+		if hr.t >= tMax {
+			return false, nil
+		}
+
+		entering := r.Direction.Dot(hr.normal) < 0
+		if fromLeft {
+			inLeft = entering
+		} else {
+			inRight = entering
+		}
+
+		if now := c.Op.inside(inLeft, inRight); now != inside {
+			inside = now
+			if hr.t > tMin {
+				if !fromLeft && c.Op == Difference {
+					// The inside of the right solid is now the outside of the combined solid.
+					hr.flip()
+				}
+				return true, hr
+			}
+		}
+
+		if fromLeft {
+			leftHit, left = c.Left.Hit(r, hr.t+bias, math.MaxFloat64)
+		} else {
+			rightHit, right = c.Right.Hit(r, hr.t+bias, math.MaxFloat64)
+		}
+	}
+	return false, nil
+}
+
+// Box returns the bounding box of the combined solid.
+func (c *CSG) Box(t0 float64, t1 float64) *AABB {
+	left := c.Left.Box(t0, t1)
+	switch c.Op {
+	case Union:
+		return left.Add(c.Right.Box(t0, t1))
+	case Intersection:
+		right := c.Right.Box(t0, t1)
+		return NewAABB(left.Min.Max(right.Min), left.Max.Min(right.Max))
+	default:
+		return left
+	}
+}
@@ -0,0 +1,42 @@
+package display
+
+import "github.com/lucasmelin/raytracer/internal/geometry"
+
+// Cutout contains a HitBoxer with holes cut out where a Mask texture is transparent, such as a Rectangle
+// textured with the picture of a leaf, a fence or a decal.
+//
+// The Mask is read as a grayscale opacity, such as the AlphaMask of an image. Rays go through the surface
+// where the opacity is below the Threshold. When Stochastic is set, rays instead hit the surface with a
+// chance equal to the opacity, rendering partially transparent surfaces with noise that averages out.
+type Cutout struct {
+	Child      HitBoxer
+	Mask       Texture
+	Threshold  float64
+	Stochastic bool
+}
+
+// NewCutout returns a new Cutout with holes where the opacity of the mask is below one half.
+func NewCutout(child HitBoxer, mask Texture) *Cutout {
+	return &Cutout{Child: child, Mask: mask, Threshold: 0.5}
+}
+
+// Hit finds the first intersection between a ray and the child surface that is not cut out.
+func (c *Cutout) Hit(r *geometry.Ray, tMin float64, tMax float64) (bool, *HitRecord) {
+	for {
+		hit, rec := c.Child.Hit(r, tMin, tMax)
+		if !hit {
+			return false, nil
+		}
+		opacity := intensity(c.Mask, rec)
+		if c.Stochastic && r.Rnd.Float64() < opacity || !c.Stochastic && opacity >= c.Threshold {
+			return true, rec
+		}
+		// Carry on past the hole.
+		tMin = rec.t + bias
+	}
+}
+
+// Box returns the bounding box of the child surface.
+func (c *Cutout) Box(t0 float64, t1 float64) *AABB {
+	return c.Child.Box(t0, t1)
+}
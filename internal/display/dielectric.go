@@ -0,0 +1,198 @@
+package display
+
+import (
+	"math"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+// RoughDielectric represents a clear material with a frosted surface, such as etched or sandblasted glass.
+//
+// Light is reflected or refracted through microfacets sampled from the GGX distribution, choosing between
+// the two with the exact Fresnel equations. The Roughness texture, read as a grayscale value, may vary
+// the roughness over the surface; a nil Roughness makes the surface perfectly smooth.
+type RoughDielectric struct {
+	RefIndex  float64
+	Roughness Texture
+	Inside    Medium // the medium filling the material, which may absorb light travelling through it
+	Priority  int    // the priority of the material where it overlaps others, see Nested
+	nonEmitter
+}
+
+// NewRoughDielectric creates a new RoughDielectric with a given index of refraction and a uniform roughness.
+func NewRoughDielectric(refIndex float64, roughness float64) RoughDielectric {
+	return RoughDielectric{RefIndex: refIndex, Roughness: NewGray(roughness)}
+}
+
+// Interior returns the medium filling the material.
+func (d RoughDielectric) Interior() Medium {
+	return d.Inside
+}
+
+// Nesting returns the priority and the index of refraction of the material.
+func (d RoughDielectric) Nesting() (int, float64) {
+	return d.Priority, d.RefIndex
+}
+
+// Scatter reflects or refracts light rays through a microfacet of the surface.
+func (d RoughDielectric) Scatter(r *geometry.Ray, rec *HitRecord) (bool, *Color, *geometry.Ray) {
+	// Work on the side of the surface the ray comes from, with eta the ratio of the index of
+	// refraction on the far side over the index on the near side.
+	basis, wo, back := localFrame(r, rec)
+	eta := d.RefIndex / rec.outer()
+	if back {
+		eta = 1 / eta
+	}
+	roughness := intensity(d.Roughness, rec)
+	dist := newGGX(roughness, roughness)
+	wi, weight, ok := scatterDielectric(wo, dist, eta, r.Rnd)
+	if !ok {
+		return false, &Color{}, &geometry.Ray{}
+	}
+	attenuation := White.Scale(weight)
+	scattered := scatterLocal(r, rec, basis, wi)
+	scattered.Spread = !dist.smooth()
+	return true, &attenuation, scattered
+}
+
+// Evaluate returns the light reflected or refracted from wi through the microfacets of the surface.
+// Smooth surfaces only reflect or refract light in single directions, and return nothing.
+func (d RoughDielectric) Evaluate(r *geometry.Ray, rec *HitRecord, wi geometry.Unit) (Color, float64) {
+	basis, wo, back := localFrame(r, rec)
+	eta := d.RefIndex / rec.outer()
+	if back {
+		eta = 1 / eta
+	}
+	roughness := intensity(d.Roughness, rec)
+	value, pdf := evaluateDielectric(wo, basis.Local(wi.Vec), newGGX(roughness, roughness), eta)
+	return White.Scale(value), pdf
+}
+
+// scatterDielectric reflects or refracts the direction wo through a microfacet sampled from dist, where eta is
+// the ratio of the index of refraction on the far side over the one on the near side. It returns the scattered
+// direction and its attenuation, or false when the scattered ray is blocked by the surface.
+//
+// Choosing between reflection and refraction in proportion to the Fresnel term cancels it out of
+// the attenuation, which is left with the masking of the scattered ray.
+func scatterDielectric(wo geometry.Vec, dist ggx, eta float64, rnd geometry.Rnd) (geometry.Vec, float64, bool) {
+	wm := dist.sampleNormal(wo, rnd)
+	var wi geometry.Vec
+	if rnd.Float64() < fresnelDielectric(wo.Dot(wm), eta) {
+		wi = reflect(wo, wm)
+		if wi.Z <= 0 {
+			return wi, 0, false
+		}
+	} else {
+		refracted, ok := refract(wo, wm, eta)
+		if !ok || refracted.Z >= 0 {
+			return wi, 0, false
+		}
+		wi = refracted
+	}
+	return wi, dist.weight(wo, wi), true
+}
+
+// evaluateDielectric returns the light scattered from wi towards wo through a microfacet of dist, reflected or
+// refracted in proportion to the Fresnel term, and the density with which scatterDielectric samples wi.
+func evaluateDielectric(wo geometry.Vec, wi geometry.Vec, dist ggx, eta float64) (float64, float64) {
+	if wi.Z > 0 {
+		wm, value, pdf, ok := dist.reflection(wo, wi)
+		if !ok {
+			return 0, 0
+		}
+		fresnel := fresnelDielectric(wo.Dot(wm), eta)
+		return fresnel * value, fresnel * pdf
+	}
+	wm, value, pdf, ok := dist.refraction(wo, wi, eta)
+	if !ok {
+		return 0, 0
+	}
+	fresnel := fresnelDielectric(wo.Dot(wm), eta)
+	return (1 - fresnel) * value, (1 - fresnel) * pdf
+}
+
+// ThinDielectric represents a clear material so thin that light passes through it without bending,
+// such as a window pane or a soap film.
+//
+// Light bouncing back and forth between both sides of the thin layer is accounted for in the
+// chance of reflecting. A rough layer blurs both the reflection and the transmission.
+type ThinDielectric struct {
+	RefIndex  float64
+	Roughness Texture
+	nonEmitter
+}
+
+// NewThinDielectric creates a new ThinDielectric with a given index of refraction and a uniform roughness.
+func NewThinDielectric(refIndex float64, roughness float64) ThinDielectric {
+	return ThinDielectric{RefIndex: refIndex, Roughness: NewGray(roughness)}
+}
+
+// Scatter reflects light rays or lets them through the layer.
+func (d ThinDielectric) Scatter(r *geometry.Ray, rec *HitRecord) (bool, *Color, *geometry.Ray) {
+	basis, wo, _ := localFrame(r, rec)
+	roughness := intensity(d.Roughness, rec)
+	dist := newGGX(roughness, roughness)
+	wm := dist.sampleNormal(wo, r.Rnd)
+
+	// Sum the light reflected after any number of bounces within the layer.
+	reflectance := fresnelDielectric(wo.Dot(wm), d.RefIndex)
+	transmittance := 1 - reflectance
+	if reflectance < 1 {
+		reflectance += transmittance * transmittance * reflectance / (1 - reflectance*reflectance)
+	}
+
+	wi := reflect(wo, wm)
+	if wi.Z <= 0 {
+		return false, &Color{}, &geometry.Ray{}
+	}
+	if r.Rnd.Float64() >= reflectance {
+		// Passing through both sides of the layer undoes the bending of the ray,
+		// so the transmitted ray mirrors the reflected one through the surface.
+		wi.Z = -wi.Z
+	}
+
+	attenuation := White.Scale(dist.weight(wo, wi))
+	return true, &attenuation, scatterLocal(r, rec, basis, wi)
+}
+
+// refract returns the direction w, pointing away from the surface, refracted through a microfacet with the
+// normal n, where eta is the ratio of the index of refraction on the far side over the one on the near side.
+// Returns false when the light is totally reflected instead.
+func refract(w geometry.Vec, n geometry.Vec, eta float64) (geometry.Vec, bool) {
+	cosI := w.Dot(n)
+	sin2T := math.Max(0, 1-cosI*cosI) / (eta * eta)
+	if sin2T >= 1 {
+		return geometry.Vec{}, false
+	}
+	cosT := math.Sqrt(1 - sin2T)
+	return w.Inv().Scale(1 / eta).Add(n.Scale(cosI/eta - cosT)), true
+}
+
+// fresnelDielectric returns the fraction of light reflected at the boundary between two dielectrics, for light
+// arriving at an angle whose cosine is cosI, where eta is the ratio of the index of refraction on the far side
+// over the one on the near side.
+func fresnelDielectric(cosI float64, eta float64) float64 {
+	cosI = clamp(cosI, -1, 1)
+	if cosI < 0 {
+		eta = 1 / eta
+		cosI = -cosI
+	}
+	sin2T := (1 - cosI*cosI) / (eta * eta)
+	if sin2T >= 1 {
+		return 1
+	}
+	cosT := math.Sqrt(1 - sin2T)
+	parallel := (eta*cosI - cosT) / (eta*cosI + cosT)
+	perpendicular := (cosI - eta*cosT) / (cosI + eta*cosT)
+	return (parallel*parallel + perpendicular*perpendicular) / 2
+}
+
+// intensity returns the average of the channels of a texture at the coordinates of a HitRecord,
+// for textures used as grayscale maps. A nil texture has an intensity of zero.
+func intensity(t Texture, rec *HitRecord) float64 {
+	if t == nil {
+		return 0
+	}
+	c := t.At(rec.u, rec.v, rec.p)
+	return (c.Red() + c.Green() + c.Blue()) / 3
+}
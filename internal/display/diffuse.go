@@ -0,0 +1,123 @@
+package display
+
+import (
+	"math"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+// OrenNayar represents a rough diffuse material, such as clay, plaster or the surface of the moon.
+//
+// The surface is made of tiny V-shaped cavities with Lambertian walls, whose slopes follow a normal
+// distribution with a standard deviation of Sigma degrees. Rough surfaces look flatter than Lambertian
+// ones and brighter when lit from behind the viewer. A Sigma of 0 is the same as a Lambertian.
+type OrenNayar struct {
+	Albedo Texture
+	Sigma  float64
+	nonEmitter
+}
+
+// NewOrenNayar creates a new OrenNayar material with a given color and roughness in degrees.
+func NewOrenNayar(albedo Texture, sigma float64) OrenNayar {
+	return OrenNayar{Albedo: albedo, Sigma: sigma}
+}
+
+// Scatter scatters light rays in a cosine-weighted pattern, weighted by the qualitative model from
+// "Generalization of Lambert's Reflectance Model" by Oren and Nayar.
+func (o OrenNayar) Scatter(r *geometry.Ray, rec *HitRecord) (bool, *Color, *geometry.Ray) {
+	basis, wo, _ := localFrame(r, rec)
+	wi := geometry.RandCosineHemisphere(r.Rnd)
+	attenuation := o.Albedo.At(rec.u, rec.v, rec.p).Scale(o.reflectance(wo, wi))
+	return true, &attenuation, spread(scatterLocal(r, rec, basis, wi))
+}
+
+// Evaluate returns the light scattered from wi, spread over the hemisphere by the cavities.
+func (o OrenNayar) Evaluate(r *geometry.Ray, rec *HitRecord, wi geometry.Unit) (Color, float64) {
+	basis, wo, _ := localFrame(r, rec)
+	local := basis.Local(wi.Vec)
+	if local.Z <= 0 {
+		return Black, 0
+	}
+	pdf := local.Z / math.Pi
+	return o.Albedo.At(rec.u, rec.v, rec.p).Scale(o.reflectance(wo, local) * pdf), pdf
+}
+
+// reflectance returns the light scattered from wi towards wo relative to a Lambertian surface,
+// both directions given in the local frame of the surface.
+func (o OrenNayar) reflectance(wo geometry.Vec, wi geometry.Vec) float64 {
+	sigma2 := radians(o.Sigma) * radians(o.Sigma)
+	a := 1 - sigma2/(2*(sigma2+0.33))
+	b := 0.45 * sigma2 / (sigma2 + 0.09)
+
+	// The cosine of the difference in azimuth between both directions.
+	var cosPhi float64
+	sinO, sinI := math.Sqrt(math.Max(0, 1-wo.Z*wo.Z)), math.Sqrt(math.Max(0, 1-wi.Z*wi.Z))
+	if sinO > 1e-4 && sinI > 1e-4 {
+		cosPhi = math.Max(0, (wo.X*wi.X+wo.Y*wi.Y)/(sinO*sinI))
+	}
+	// alpha is the larger of the two angles to the normal and beta the smaller.
+	var sinAlpha, tanBeta float64
+	if math.Abs(wi.Z) > math.Abs(wo.Z) {
+		sinAlpha, tanBeta = sinO, sinI/math.Abs(wi.Z)
+	} else {
+		sinAlpha, tanBeta = sinI, sinO/math.Max(math.Abs(wo.Z), 1e-6)
+	}
+	return a + b*cosPhi*sinAlpha*tanBeta
+}
+
+// Retroreflective represents a diffuse material that sends part of the light straight back where it came from,
+// such as road signs or high-visibility clothing covered in tiny glass beads.
+//
+// Retro is the fraction of light sent back, as a grayscale texture, and Sharpness narrows the returned beam.
+// The rest of the light is scattered like a Lambertian.
+type Retroreflective struct {
+	Albedo    Texture
+	Retro     Texture
+	Sharpness float64
+	nonEmitter
+}
+
+// NewRetroreflective creates a new Retroreflective material with a given color, fraction of light sent back
+// and sharpness of the returned beam.
+func NewRetroreflective(albedo Texture, retro float64, sharpness float64) Retroreflective {
+	return Retroreflective{Albedo: albedo, Retro: NewGray(retro), Sharpness: sharpness}
+}
+
+// Scatter scatters light rays either back towards where they came from or in a cosine-weighted pattern.
+func (rr Retroreflective) Scatter(r *geometry.Ray, rec *HitRecord) (bool, *Color, *geometry.Ray) {
+	basis, wo, _ := localFrame(r, rec)
+	attenuation := rr.Albedo.At(rec.u, rec.v, rec.p)
+
+	if r.Rnd.Float64() < intensity(rr.Retro, rec) {
+		// Sample a cosine power lobe around the direction towards the viewer.
+		cosTheta := math.Pow(r.Rnd.Float64(), 1/(rr.Sharpness+1))
+		sinTheta := math.Sqrt(math.Max(0, 1-cosTheta*cosTheta))
+		phi := 2 * math.Pi * r.Rnd.Float64()
+		lobe := geometry.NewBasis(wo.ToUnit())
+		wi := lobe.World(geometry.NewVec(sinTheta*math.Cos(phi), sinTheta*math.Sin(phi), cosTheta))
+		if wi.Z > 0 {
+			return true, &attenuation, spread(scatterLocal(r, rec, basis, wi))
+		}
+	}
+
+	wi := geometry.RandCosineHemisphere(r.Rnd)
+	return true, &attenuation, spread(scatterLocal(r, rec, basis, wi))
+}
+
+// Evaluate returns the light scattered from wi by the beam sent back towards the viewer and by the diffuse
+// lobe. The small share of the beam falling below the surface, which Scatter spreads diffusely instead,
+// is left out.
+func (rr Retroreflective) Evaluate(r *geometry.Ray, rec *HitRecord, wi geometry.Unit) (Color, float64) {
+	basis, wo, _ := localFrame(r, rec)
+	local := basis.Local(wi.Vec)
+	if local.Z <= 0 {
+		return Black, 0
+	}
+	retro := intensity(rr.Retro, rec)
+	pdf := (1 - retro) * local.Z / math.Pi
+	if cos := local.Dot(wo); cos > 0 {
+		pdf += retro * (rr.Sharpness + 1) / (2 * math.Pi) * math.Pow(cos, rr.Sharpness)
+	}
+	// Every direction is scattered with the same attenuation, so the light follows the density.
+	return rr.Albedo.At(rec.u, rec.v, rec.p).Scale(pdf), pdf
+}
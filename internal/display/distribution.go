@@ -0,0 +1,87 @@
+package display
+
+import (
+	"math"
+	"sort"
+)
+
+// distribution1D represents a piecewise-constant density over [0, 1), split into as many equal intervals as
+// values, with each interval sampled in proportion to its value.
+type distribution1D struct {
+	values []float64
+	cdf    []float64 // the share of the total up to the start of each interval, and 1 at the end
+	total  float64   // the integral of the values over [0, 1)
+}
+
+// newDistribution1D returns a new distribution1D sampling intervals in proportion to the values, or uniformly
+// when they are all 0.
+func newDistribution1D(values []float64) distribution1D {
+	n := len(values)
+	d := distribution1D{values: values, cdf: make([]float64, n+1)}
+	for i, v := range values {
+		d.cdf[i+1] = d.cdf[i] + v/float64(n)
+	}
+	d.total = d.cdf[n]
+	for i := 1; i <= n; i++ {
+		if d.total > 0 {
+			d.cdf[i] /= d.total
+		} else {
+			d.cdf[i] = float64(i) / float64(n)
+		}
+	}
+	return d
+}
+
+// sample returns a point of [0, 1) for a random number u, along with its density and its interval.
+func (d distribution1D) sample(u float64) (float64, float64, int) {
+	// The last interval whose cdf does not exceed u.
+	i := sort.Search(len(d.cdf), func(i int) bool { return d.cdf[i] > u }) - 1
+	i = clampIndex(i, len(d.values)-1)
+	offset := u - d.cdf[i]
+	if width := d.cdf[i+1] - d.cdf[i]; width > 0 {
+		offset /= width
+	}
+	x := (float64(i) + offset) / float64(len(d.values))
+	return math.Min(x, math.Nextafter(1, 0)), d.pdf(i), i
+}
+
+// pdf returns the density of the interval i.
+func (d distribution1D) pdf(i int) float64 {
+	if d.total <= 0 {
+		return 1
+	}
+	return d.values[i] / d.total
+}
+
+// distribution2D represents a piecewise-constant density over [0, 1) x [0, 1), sampling a row of a grid of
+// values in proportion to its total, then a column within the row.
+type distribution2D struct {
+	rows     []distribution1D // the distribution of the columns of each row
+	marginal distribution1D   // the distribution of the rows
+}
+
+// newDistribution2D returns a new distribution2D of the values of a grid stored row by row.
+func newDistribution2D(values []float64, width int, height int) distribution2D {
+	d := distribution2D{rows: make([]distribution1D, height)}
+	totals := make([]float64, height)
+	for y := range d.rows {
+		d.rows[y] = newDistribution1D(values[y*width : (y+1)*width])
+		totals[y] = d.rows[y].total
+	}
+	d.marginal = newDistribution1D(totals)
+	return d
+}
+
+// sample returns a point of [0, 1) x [0, 1) for two random numbers, along with its density.
+func (d distribution2D) sample(u1 float64, u2 float64) (float64, float64, float64) {
+	y, pdfY, row := d.marginal.sample(u2)
+	x, pdfX, _ := d.rows[row].sample(u1)
+	return x, y, pdfX * pdfY
+}
+
+// pdf returns the density of the point (x, y).
+func (d distribution2D) pdf(x float64, y float64) float64 {
+	row := clampIndex(int(y*float64(len(d.rows))), len(d.rows)-1)
+	column := clampIndex(int(x*float64(len(d.rows[row].values))), len(d.rows[row].values)-1)
+	return d.marginal.pdf(row) * d.rows[row].pdf(column)
+}
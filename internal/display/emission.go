@@ -0,0 +1,149 @@
+package display
+
+import (
+	"math"
+	"sync"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+// Emissive represents a surface that glows, on top of scattering light like its Base material.
+//
+// The light emitted is the Emission texture scaled by Intensity, so any texture can be used as a source of
+// light, such as an image on a screen or Perlin noise for lava. Unless TwoSided is set, light is only emitted
+// from the outside of the surface, where its normal points.
+//
+// A Profile makes the surface emit the candela distribution of a measured fixture, with its nadir along the
+// normal and its horizontal angles turning from the U direction of the surface. As a flat surface seen at an
+// angle looks smaller, the light is divided by the cosine to the normal, up to grazing angles where it would
+// blow up. The surface keeps the power it would have without a profile, as long as the fixture only lights
+// the side it faces.
+type Emissive struct {
+	Base      Material // the material scattering light off the surface, or nil for a surface that only emits
+	Emission  Texture
+	Intensity float64
+	TwoSided  bool
+	Profile   *IESProfile
+}
+
+// emissiveGrazing is the smallest cosine to the normal that the light of an Emissive with a Profile is
+// divided by.
+const emissiveGrazing = 0.05
+
+// NewEmissive creates a new Emissive glowing from the outside of a base material, with a given
+// emission texture and intensity.
+func NewEmissive(base Material, emission Texture, intensity float64) Emissive {
+	return Emissive{Base: base, Emission: emission, Intensity: intensity}
+}
+
+// Scatter scatters light rays off the base material.
+func (e Emissive) Scatter(r *geometry.Ray, rec *HitRecord) (bool, *Color, *geometry.Ray) {
+	if e.Base == nil {
+		return false, &Color{}, &geometry.Ray{}
+	}
+	return e.Base.Scatter(r, rec)
+}
+
+// Evaluate returns the light scattered from wi by the base material, if any.
+func (e Emissive) Evaluate(r *geometry.Ray, rec *HitRecord, wi geometry.Unit) (Color, float64) {
+	if e.Base == nil {
+		return Black, 0
+	}
+	return evaluate(e.Base, r, rec, wi)
+}
+
+// Emit returns the light emitted towards the ray r, along with any light emitted by the base material.
+func (e Emissive) Emit(r *geometry.Ray, rec *HitRecord) Color {
+	emitted := Black
+	if e.Base != nil {
+		emitted = e.Base.Emit(r, rec)
+	}
+	if !e.TwoSided && r.Direction.Dot(rec.normal) > 0 {
+		return emitted
+	}
+	intensity := e.Intensity
+	if e.Profile != nil {
+		normal := rec.normal
+		if r.Direction.Dot(normal) > 0 {
+			normal = normal.Inv()
+		}
+		out := r.Direction.Inv().ToUnit()
+		cos := math.Max(out.Dot(normal), emissiveGrazing)
+		// The relative intensity integrates to 4π over the sphere, against π for the cosines of a constant light.
+		intensity *= e.Profile.relative(geometry.NewBasisFromTangent(normal, rec.dpdu), out.Vec) / (4 * cos)
+	}
+	return emitted.Add(e.Emission.At(rec.u, rec.v, rec.p).Scale(intensity))
+}
+
+// wrapped returns the base material, so that a glowing glass still encloses the medium of the glass.
+func (e Emissive) wrapped() []Material {
+	return []Material{e.Base}
+}
+
+// NewBlackbody returns a new Solid of the color of light emitted by a black body at a temperature in kelvin,
+// such as 1800K for a candle flame, 2700K for an incandescent bulb or 6500K for daylight.
+func NewBlackbody(kelvin float64) Solid {
+	return NewSolid(BlackbodyColor(kelvin))
+}
+
+// BlackbodyColor returns the color of light emitted by a black body at a temperature in kelvin, following
+// Planck's law. The color is normalised to a luminance of one so that temperature only changes the hue.
+func BlackbodyColor(kelvin float64) Color {
+	xyz := blackbodyXYZ(kelvin)
+	if xyz.Y == 0 {
+		return Black
+	}
+	rgb := xyzToRGB(xyz.Scale(1 / xyz.Y)).Div(spectralWhite.rgb)
+	return Color{Vec: rgb.Max(geometry.Vec{})}
+}
+
+// blackbodyXYZ returns the CIE XYZ color of the light emitted by a black body at a temperature in kelvin.
+func blackbodyXYZ(kelvin float64) geometry.Vec {
+	xyz := geometry.Vec{}
+	if kelvin <= 0 {
+		return xyz
+	}
+	for lambda := minWavelength; lambda < maxWavelength; lambda++ {
+		xyz = xyz.Add(colorMatch(lambda + 0.5).Scale(planck(lambda+0.5, kelvin)))
+	}
+	return xyz
+}
+
+// blackbodyStep and blackbodyMax set the temperatures in kelvin tabulated by blackbodyGlow.
+const (
+	blackbodyStep = 25
+	blackbodyMax  = 12000
+)
+
+// blackbodyTable holds the light emitted by a black body at every blackbodyStep kelvin up to blackbodyMax,
+// relative to one at 6500K.
+var blackbodyTable struct {
+	sync.Once
+	glow []Color
+}
+
+// blackbodyGlow returns the light emitted by a black body at a temperature in kelvin, relative to one at
+// 6500K. Unlike BlackbodyColor, the brightness follows the temperature, so that a body at 1000K only glows
+// a faint red. Temperatures above blackbodyMax glow as at blackbodyMax.
+func blackbodyGlow(kelvin float64) Color {
+	blackbodyTable.Do(func() {
+		reference := blackbodyXYZ(6500).Y
+		for k := 0; k <= blackbodyMax; k += blackbodyStep {
+			blackbodyTable.glow = append(blackbodyTable.glow, BlackbodyColor(float64(k)).Scale(blackbodyXYZ(float64(k)).Y/reference))
+		}
+	})
+	x := clamp(kelvin, 0, blackbodyMax) / blackbodyStep
+	i := clampIndex(int(x), len(blackbodyTable.glow)-2)
+	return blend(blackbodyTable.glow[i], blackbodyTable.glow[i+1], x-float64(i))
+}
+
+// planck returns the spectral radiance of a black body at a temperature in kelvin, at a wavelength in nanometres.
+func planck(lambda float64, kelvin float64) float64 {
+	const (
+		c  = 299792458.0    // the speed of light in m/s
+		h  = 6.62607015e-34 // Planck's constant in J s
+		kb = 1.380649e-23   // Boltzmann's constant in J/K
+	)
+	l := lambda * 1e-9
+	return 2 * h * c * c / (l * l * l * l * l * (math.Exp(h*c/(l*kb*kelvin)) - 1))
+}
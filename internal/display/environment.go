@@ -0,0 +1,110 @@
+package display
+
+import (
+	"math"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+// Environment represents light arriving from infinitely far away in every direction, such as the sky, given
+// by an HDR image in the equirectangular projection: the columns of the image span the directions around
+// the Y axis and the rows span the directions from straight up at the top to straight down at the bottom.
+// The middle of the image lies towards -Z, and Rotation turns the environment around the Y axis by a number
+// of degrees. Intensity scales the light of the image.
+//
+// As a LightSource, the Environment is sampled in proportion to the light of each pixel, so that a small and
+// bright sun in the image lights the scene with little noise.
+type Environment struct {
+	Image     *HDR
+	Rotation  float64
+	Intensity float64
+	dist      distribution2D
+}
+
+// NewEnvironment returns a new Environment from an HDR image, turned by a rotation in degrees around the Y
+// axis and scaled by an intensity.
+func NewEnvironment(img *HDR, rotation float64, intensity float64) *Environment {
+	values := make([]float64, len(img.Pixels))
+	for y := 0; y < img.Height; y++ {
+		// Rows towards the poles cover less of the sphere.
+		sin := math.Sin(math.Pi * (float64(y) + 0.5) / float64(img.Height))
+		for x := 0; x < img.Width; x++ {
+			luminance := img.Pixels[y*img.Width+x].Luminance()
+			if luminance > 0 && !math.IsInf(luminance, 1) {
+				values[y*img.Width+x] = luminance * sin
+			}
+		}
+	}
+	return &Environment{
+		Image:     img,
+		Rotation:  rotation,
+		Intensity: intensity,
+		dist:      newDistribution2D(values, img.Width, img.Height),
+	}
+}
+
+// Radiance returns the light seen by a ray travelling in the direction dir.
+func (e *Environment) Radiance(dir geometry.Unit) Color {
+	u, v := e.coordinates(dir)
+	x := clampIndex(int(u*float64(e.Image.Width)), e.Image.Width-1)
+	y := clampIndex(int(v*float64(e.Image.Height)), e.Image.Height-1)
+	return e.Image.Pixels[y*e.Image.Width+x].Scale(e.Intensity)
+}
+
+// Sample returns the light arriving at p from a direction of the environment picked in proportion to its
+// light.
+func (e *Environment) Sample(p geometry.Vec, rnd geometry.Rnd) LightSample {
+	u, v, pdf := e.dist.sample(rnd.Float64(), rnd.Float64())
+	dir, sin := e.direction(u, v)
+	if pdf <= 0 || sin <= 0 {
+		return LightSample{Direction: dir, Distance: math.Inf(1), Light: Black}
+	}
+	pdf /= 2 * math.Pi * math.Pi * sin
+	return LightSample{
+		Direction: dir,
+		Distance:  math.Inf(1),
+		Light:     e.Radiance(dir).Scale(1 / pdf),
+		PDF:       pdf,
+	}
+}
+
+// PDF returns the density with which Sample picks the direction dir towards the environment.
+func (e *Environment) PDF(dir geometry.Unit) float64 {
+	u, v := e.coordinates(dir)
+	sin := math.Sin(math.Pi * v)
+	if sin <= 0 {
+		return 0
+	}
+	return e.dist.pdf(u, v) / (2 * math.Pi * math.Pi * sin)
+}
+
+// coordinates returns the coordinates in the image of the direction dir.
+func (e *Environment) coordinates(dir geometry.Unit) (float64, float64) {
+	return equirectangular(dir, e.Rotation)
+}
+
+// direction returns the direction at the given coordinates of the image, along with the sine of its angle
+// to the Y axis.
+func (e *Environment) direction(u float64, v float64) (geometry.Unit, float64) {
+	return fromEquirectangular(u, v, e.Rotation)
+}
+
+// equirectangular returns the coordinates of the direction dir in the equirectangular projection turned by
+// a rotation in degrees around the Y axis, from 0 to 1 left to right and top to bottom.
+func equirectangular(dir geometry.Unit, rotation float64) (float64, float64) {
+	phi := math.Atan2(dir.X, -dir.Z) - rotation*math.Pi/180
+	u := math.Mod(0.5+phi/(2*math.Pi), 1)
+	if u < 0 {
+		u++
+	}
+	return u, math.Acos(clamp(dir.Y, -1, 1)) / math.Pi
+}
+
+// fromEquirectangular returns the direction at the given coordinates of the equirectangular projection
+// turned by a rotation in degrees around the Y axis, along with the sine of its angle to the Y axis.
+func fromEquirectangular(u float64, v float64, rotation float64) (geometry.Unit, float64) {
+	phi := (u-0.5)*2*math.Pi + rotation*math.Pi/180
+	theta := v * math.Pi
+	sin := math.Sin(theta)
+	return geometry.NewUnit(sin*math.Sin(phi), math.Cos(theta), -sin*math.Cos(phi)), sin
+}
@@ -0,0 +1,44 @@
+package display
+
+import (
+	"math"
+	"testing"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+func TestEnvironment(t *testing.T) {
+	// A dim sky with a small and bright sun.
+	const width, height = 32, 16
+	img := &HDR{Width: width, Height: height, Pixels: make([]Color, width*height)}
+	for i := range img.Pixels {
+		img.Pixels[i] = NewColor(0.2, 0.3, 0.5)
+	}
+	img.Pixels[4*width+20] = NewColor(5000, 4000, 3000)
+	env := NewEnvironment(img, 30, 2)
+
+	// The light of the environment integrated over the sphere, and the integral of its PDF.
+	irradiance := sphereIntegral(func(dir geometry.Unit) float64 {
+		return env.Radiance(dir).Red()
+	})
+	if density := sphereIntegral(env.PDF); math.Abs(density-1) > 1e-2 {
+		t.Errorf("PDF() integrates to %v, want 1", density)
+	}
+	checkSampling(t, env, irradiance, 20000, 1e-2)
+}
+
+func TestEnvironmentRotation(t *testing.T) {
+	const width, height = 8, 4
+	img := &HDR{Width: width, Height: height, Pixels: make([]Color, width*height)}
+	for i := range img.Pixels {
+		img.Pixels[i] = NewColor(float64(i%width), 0, 0)
+	}
+	// Turning the environment by a full turn more leaves it where it was.
+	dir := geometry.NewUnit(0.6, 0.2, math.Sqrt(1-0.36-0.04))
+	for _, rotation := range []float64{30, 200, -150} {
+		want := NewEnvironment(img, rotation, 1).Radiance(dir)
+		if got := NewEnvironment(img, rotation+360, 1).Radiance(dir); got != want {
+			t.Errorf("Radiance() turned by %v = %v, want %v", rotation+360, got, want)
+		}
+	}
+}
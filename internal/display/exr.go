@@ -0,0 +1,324 @@
+package display
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// exrMagic is the number every OpenEXR file starts with.
+const exrMagic = 20000630
+
+// Compression methods of OpenEXR files.
+const (
+	exrNoCompression   = 0
+	exrRLECompression  = 1
+	exrZIPSCompression = 2
+	exrZIPCompression  = 3
+)
+
+// exrChannel represents a channel of the pixels of an OpenEXR file.
+type exrChannel struct {
+	name      string
+	pixelType int32 // 0 for 32-bit unsigned integers, 1 for 16-bit floats and 2 for 32-bit floats
+}
+
+// size returns the number of bytes of each value of the channel.
+func (c exrChannel) size() int {
+	if c.pixelType == 1 {
+		return 2
+	}
+	return 4
+}
+
+// exrReader reads little-endian values from the bytes of an OpenEXR file, recording the first error.
+type exrReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+// bytes returns the next n bytes.
+func (r *exrReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.pos+n > len(r.data) {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+// int32 returns the next 32-bit integer, or 0 after an error.
+func (r *exrReader) int32() int32 {
+	b := r.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return int32(binary.LittleEndian.Uint32(b))
+}
+
+// uint64 returns the next 64-bit integer, or 0 after an error.
+func (r *exrReader) uint64() uint64 {
+	b := r.bytes(8)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+
+// cstring returns the next string ended by a zero byte, without it.
+func (r *exrReader) cstring() string {
+	if r.err != nil {
+		return ""
+	}
+	end := bytes.IndexByte(r.data[r.pos:], 0)
+	if end < 0 {
+		r.err = io.ErrUnexpectedEOF
+		return ""
+	}
+	s := string(r.data[r.pos : r.pos+end])
+	r.pos += end + 1
+	return s
+}
+
+// parseEXR reads an HDR image from a single-part OpenEXR file made of scanlines.
+//
+// The red, green and blue channels are read into the image, or the luminance channel Y for grayscale
+// images, in 16-bit or 32-bit floats. The pixels may be uncompressed or compressed with RLE, ZIPS or ZIP,
+// which covers the files written by most tools; tiled files and other compression methods are not supported.
+func parseEXR(rd io.Reader) (*HDR, error) {
+	data, err := io.ReadAll(rd)
+	if err != nil {
+		return nil, err
+	}
+	r := &exrReader{data: data}
+	if r.int32() != exrMagic {
+		return nil, errors.New("not an OpenEXR file")
+	}
+	version := r.int32()
+	if version&0xff != 2 {
+		return nil, fmt.Errorf("unsupported version %d", version&0xff)
+	}
+	if version&0x1a00 != 0 {
+		return nil, errors.New("tiled, deep and multi-part files are not supported")
+	}
+
+	var channels []exrChannel
+	compression := -1
+	var window [4]int32
+	hasWindow := false
+	for {
+		name := r.cstring()
+		if name == "" || r.err != nil {
+			break
+		}
+		r.cstring() // the type of the attribute, implied by its name
+		value := &exrReader{data: r.bytes(int(r.int32()))}
+		switch name {
+		case "channels":
+			for {
+				name := value.cstring()
+				if name == "" || value.err != nil {
+					break
+				}
+				pixelType := value.int32()
+				value.bytes(4) // linearity and reserved bytes
+				xSampling, ySampling := value.int32(), value.int32()
+				if xSampling != 1 || ySampling != 1 {
+					return nil, fmt.Errorf("subsampled channel %q is not supported", name)
+				}
+				if pixelType < 0 || pixelType > 2 {
+					return nil, fmt.Errorf("invalid pixel type %d of channel %q", pixelType, name)
+				}
+				channels = append(channels, exrChannel{name: name, pixelType: pixelType})
+			}
+		case "compression":
+			if b := value.bytes(1); b != nil {
+				compression = int(b[0])
+			}
+		case "dataWindow":
+			for i := range window {
+				window[i] = value.int32()
+			}
+			hasWindow = true
+		}
+		if value.err != nil {
+			return nil, fmt.Errorf("reading attribute %q: %w", name, value.err)
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("reading header: %w", r.err)
+	}
+	if len(channels) == 0 || compression < 0 || !hasWindow {
+		return nil, errors.New("missing channels, compression or data window")
+	}
+
+	linesPerChunk := 1
+	switch compression {
+	case exrNoCompression, exrRLECompression, exrZIPSCompression:
+	case exrZIPCompression:
+		linesPerChunk = 16
+	default:
+		return nil, fmt.Errorf("unsupported compression %d", compression)
+	}
+	width, height := int(window[2])-int(window[0])+1, int(window[3])-int(window[1])+1
+	if width < 1 || height < 1 || width*height > 1<<28 {
+		return nil, fmt.Errorf("invalid size %dx%d", width, height)
+	}
+	pixelSize := 0
+	for _, c := range channels {
+		pixelSize += c.size()
+	}
+
+	h := HDR{Width: width, Height: height, Pixels: make([]Color, width*height)}
+	chunks := (height + linesPerChunk - 1) / linesPerChunk
+	offsets := make([]uint64, chunks)
+	for i := range offsets {
+		offsets[i] = r.uint64()
+	}
+	for _, offset := range offsets {
+		if offset > uint64(len(data)) {
+			return nil, errors.New("chunk offset out of the file")
+		}
+		chunk := &exrReader{data: data, pos: int(offset)}
+		y := int(chunk.int32()) - int(window[1])
+		packed := chunk.bytes(int(chunk.int32()))
+		if chunk.err != nil {
+			return nil, fmt.Errorf("reading chunk: %w", chunk.err)
+		}
+		if y < 0 || y >= height {
+			return nil, fmt.Errorf("chunk at row %d out of the image", y)
+		}
+		lines := linesPerChunk
+		if y+lines > height {
+			lines = height - y
+		}
+		pixels, err := exrUncompress(packed, compression, lines*width*pixelSize)
+		if err != nil {
+			return nil, fmt.Errorf("uncompressing row %d: %w", y, err)
+		}
+		exrReadLines(&h, pixels, channels, y, lines)
+	}
+	return &h, nil
+}
+
+// exrUncompress returns the size bytes of the pixels of a chunk from their compressed form.
+func exrUncompress(packed []byte, compression int, size int) ([]byte, error) {
+	if len(packed) >= size {
+		// Chunks that do not shrink when compressed are stored as is.
+		return packed[:size], nil
+	}
+	var out []byte
+	switch compression {
+	case exrRLECompression:
+		for i := 0; i < len(packed); {
+			count := int(int8(packed[i]))
+			if count < 0 {
+				if i+1-count > len(packed) {
+					return nil, io.ErrUnexpectedEOF
+				}
+				out = append(out, packed[i+1:i+1-count]...)
+				i += 1 - count
+				continue
+			}
+			if i+1 >= len(packed) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			for j := 0; j <= count; j++ {
+				out = append(out, packed[i+1])
+			}
+			i += 2
+		}
+	case exrZIPSCompression, exrZIPCompression:
+		z, err := zlib.NewReader(bytes.NewReader(packed))
+		if err != nil {
+			return nil, err
+		}
+		out = make([]byte, size)
+		if _, err := io.ReadFull(z, out); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported compression %d", compression)
+	}
+	if len(out) != size {
+		return nil, fmt.Errorf("got %d bytes, want %d", len(out), size)
+	}
+
+	// Undo the differences between successive bytes, then the split of the bytes into two halves.
+	for i := 1; i < len(out); i++ {
+		out[i] = out[i-1] + out[i] - 128
+	}
+	pixels := make([]byte, size)
+	half := (size + 1) / 2
+	for i := range pixels {
+		if i%2 == 0 {
+			pixels[i] = out[i/2]
+		} else {
+			pixels[i] = out[half+i/2]
+		}
+	}
+	return pixels, nil
+}
+
+// exrReadLines reads lines of pixels starting at row y into the image, each line holding the values of each
+// channel in turn.
+func exrReadLines(h *HDR, pixels []byte, channels []exrChannel, y int, lines int) {
+	pos := 0
+	for line := 0; line < lines; line++ {
+		row := h.Pixels[(y+line)*h.Width : (y+line+1)*h.Width]
+		for _, c := range channels {
+			for x := range row {
+				var v float64
+				switch c.pixelType {
+				case 0:
+					v = float64(binary.LittleEndian.Uint32(pixels[pos:]))
+				case 1:
+					v = halfToFloat(binary.LittleEndian.Uint16(pixels[pos:]))
+				default:
+					v = float64(math.Float32frombits(binary.LittleEndian.Uint32(pixels[pos:])))
+				}
+				pos += c.size()
+				switch c.name {
+				case "R":
+					row[x].X = v
+				case "G":
+					row[x].Y = v
+				case "B":
+					row[x].Z = v
+				case "Y":
+					row[x] = NewColor(v, v, v)
+				}
+			}
+		}
+	}
+}
+
+// halfToFloat returns the value of a 16-bit floating point number.
+func halfToFloat(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exponent := int(h>>10) & 0x1f
+	mantissa := float64(h & 0x3ff)
+	switch exponent {
+	case 0:
+		return sign * math.Ldexp(mantissa, -24)
+	case 0x1f:
+		if mantissa == 0 {
+			return sign * math.Inf(1)
+		}
+		return math.NaN()
+	default:
+		return sign * math.Ldexp(1024+mantissa, exponent-25)
+	}
+}
@@ -12,11 +12,11 @@ func NewFlip(child HitBoxer) *Flip {
 	return &Flip{Child: child}
 }
 
-// Hit calculates if the ray hits the HitBoxer. If so, the normal of the HitRecord is inverted.
+// Hit calculates if the ray hits the HitBoxer. If so, the HitRecord is flipped to the other side of the surface.
 func (f *Flip) Hit(r *geometry.Ray, tMin float64, tMax float64) (bool, *HitRecord) {
 	hit, record := f.Child.Hit(r, tMin, tMax)
 	if hit {
-		record.normal = record.normal.Inv()
+		record.flip()
 	}
 	return hit, record
 }
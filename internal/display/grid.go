@@ -0,0 +1,138 @@
+package display
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+// Grid represents a dense grid of values spread evenly through the box from Min to Max, such as the densities
+// of a simulated cloud of smoke. The first sample of the grid sits at Min and the last one at Max, and values
+// between samples are interpolated trilinearly. Points outside the box have a value of 0.
+type Grid struct {
+	Min    geometry.Vec
+	Max    geometry.Vec
+	nx     int
+	ny     int
+	nz     int
+	values []float64 // value of each sample, along X first, then Y, then Z
+	max    float64   // highest value of all samples
+}
+
+// NewGrid returns a new Grid from nx by ny by nz values, stored along X first, then Y, then Z.
+func NewGrid(nx int, ny int, nz int, values []float64, min geometry.Vec, max geometry.Vec) *Grid {
+	if nx < 2 || ny < 2 || nz < 2 || len(values) != nx*ny*nz {
+		panic("grid needs at least 2x2x2 values")
+	}
+	g := Grid{Min: min, Max: max, nx: nx, ny: ny, nz: nz, values: values}
+	for _, v := range values {
+		g.max = math.Max(g.max, v)
+	}
+	return &g
+}
+
+// LoadVol reads a Grid from a Mitsuba .vol file.
+func LoadVol(path string) (*Grid, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return parseVol(bufio.NewReader(f), info.Size())
+}
+
+// maxVolValues is the largest number of values read from a .vol file, channels included.
+const maxVolValues = 1 << 30
+
+// parseVol reads a Grid from the binary .vol format used by Mitsuba.
+//
+// The file starts with the bytes "VOL" and the version 3, followed by little-endian 32-bit integers for the
+// encoding of the values (1 for 32-bit floats), the number of samples along X, Y and Z and the number of
+// channels, then 32-bit floats for the corners of the box, and finally the values themselves.
+// The channels of each sample are averaged. The size of the input in bytes bounds the number of values
+// the header may announce, so that a corrupt header does not allocate more memory than the file could fill.
+func parseVol(r io.Reader, size int64) (*Grid, error) {
+	var header struct {
+		Magic    [3]byte
+		Version  uint8
+		Encoding int32
+		Res      [3]int32
+		Channels int32
+		Box      [6]float32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	if string(header.Magic[:]) != "VOL" || header.Version != 3 {
+		return nil, errors.New("not a version 3 .vol file")
+	}
+	if header.Encoding != 1 {
+		return nil, fmt.Errorf("unsupported encoding %d, only 32-bit floats are supported", header.Encoding)
+	}
+	nx, ny, nz, channels := int(header.Res[0]), int(header.Res[1]), int(header.Res[2]), int(header.Channels)
+	// The count is taken in floating point, where the product of four 32-bit integers cannot overflow.
+	count := float64(nx) * float64(ny) * float64(nz) * float64(channels)
+	if nx < 2 || ny < 2 || nz < 2 || channels < 1 || count > maxVolValues {
+		return nil, fmt.Errorf("invalid size %dx%dx%d with %d channels", nx, ny, nz, channels)
+	}
+	if remaining := size - int64(binary.Size(header)); int64(count)*4 > remaining {
+		return nil, fmt.Errorf("%dx%dx%d samples with %d channels need %d bytes of values, but only %d remain",
+			nx, ny, nz, channels, int64(count)*4, remaining)
+	}
+
+	raw := make([]float32, nx*ny*nz*channels)
+	if err := binary.Read(r, binary.LittleEndian, raw); err != nil {
+		return nil, fmt.Errorf("reading values: %w", err)
+	}
+	values := make([]float64, nx*ny*nz)
+	for k := range values {
+		var sum float64
+		for c := 0; c < channels; c++ {
+			sum += float64(raw[k*channels+c])
+		}
+		values[k] = sum / float64(channels)
+	}
+	b := header.Box
+	min := geometry.NewVec(float64(b[0]), float64(b[1]), float64(b[2]))
+	max := geometry.NewVec(float64(b[3]), float64(b[4]), float64(b[5]))
+	return NewGrid(nx, ny, nz, values, min, max), nil
+}
+
+// Density returns the value of the grid at the point p.
+func (g *Grid) Density(p geometry.Vec) float64 {
+	if p.X < g.Min.X || p.Y < g.Min.Y || p.Z < g.Min.Z || p.X > g.Max.X || p.Y > g.Max.Y || p.Z > g.Max.Z {
+		return 0
+	}
+	// Position of p in units of samples.
+	x := (p.X - g.Min.X) / (g.Max.X - g.Min.X) * float64(g.nx-1)
+	y := (p.Y - g.Min.Y) / (g.Max.Y - g.Min.Y) * float64(g.ny-1)
+	z := (p.Z - g.Min.Z) / (g.Max.Z - g.Min.Z) * float64(g.nz-1)
+	i := clampIndex(int(x), g.nx-2)
+	j := clampIndex(int(y), g.ny-2)
+	k := clampIndex(int(z), g.nz-2)
+	fx, fy, fz := x-float64(i), y-float64(j), z-float64(k)
+
+	at := func(di int, dj int, dk int) float64 {
+		return g.values[((k+dk)*g.ny+j+dj)*g.nx+i+di]
+	}
+	return lerp(
+		lerp(lerp(at(0, 0, 0), at(1, 0, 0), fx), lerp(at(0, 1, 0), at(1, 1, 0), fx), fy),
+		lerp(lerp(at(0, 0, 1), at(1, 0, 1), fx), lerp(at(0, 1, 1), at(1, 1, 1), fx), fy),
+		fz,
+	)
+}
+
+// Majorant returns the highest value of the grid.
+func (g *Grid) Majorant() float64 {
+	return g.max
+}
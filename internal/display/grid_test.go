@@ -0,0 +1,79 @@
+package display
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+// vol returns the bytes of a .vol file with a given header and values.
+func vol(magic string, encoding int32, res [3]int32, channels int32, values []float32) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(magic)
+	buf.WriteByte(3)
+	for _, v := range []interface{}{encoding, res, channels, [6]float32{0, 0, 0, 2, 4, 6}, values} {
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+	return buf.Bytes()
+}
+
+func TestParseVol(t *testing.T) {
+	// Two channels averaging to the index of each sample.
+	var values []float32
+	for k := 0; k < 8; k++ {
+		values = append(values, float32(k)-1, float32(k)+1)
+	}
+	data := vol("VOL", 1, [3]int32{2, 2, 2}, 2, values)
+	grid, err := parseVol(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		p    geometry.Vec
+		want float64
+	}{
+		{name: "first sample", p: geometry.NewVec(0, 0, 0), want: 0},
+		{name: "last sample", p: geometry.NewVec(2, 4, 6), want: 7},
+		{name: "along X", p: geometry.NewVec(1, 0, 0), want: 0.5},
+		{name: "along Y", p: geometry.NewVec(0, 2, 0), want: 1},
+		{name: "along Z", p: geometry.NewVec(0, 0, 3), want: 2},
+		{name: "centre", p: geometry.NewVec(1, 2, 3), want: 3.5},
+		{name: "outside", p: geometry.NewVec(-1, 2, 3), want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := grid.Density(tt.p); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Density() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := grid.Majorant(); got != 7 {
+		t.Errorf("Majorant() = %v, want 7", got)
+	}
+}
+
+func TestParseVolErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "wrong magic", data: vol("BOX", 1, [3]int32{2, 2, 2}, 1, make([]float32, 8))},
+		{name: "half floats", data: vol("VOL", 2, [3]int32{2, 2, 2}, 1, make([]float32, 8))},
+		{name: "too small", data: vol("VOL", 1, [3]int32{1, 2, 2}, 1, make([]float32, 4))},
+		{name: "truncated", data: vol("VOL", 1, [3]int32{2, 2, 2}, 1, make([]float32, 7))},
+		{name: "too many values", data: vol("VOL", 1, [3]int32{1 << 20, 1 << 20, 1 << 20}, 1<<30, make([]float32, 8))},
+		{name: "larger than the file", data: vol("VOL", 1, [3]int32{1024, 1024, 256}, 1, make([]float32, 8))},
+		{name: "empty", data: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseVol(bytes.NewReader(tt.data), int64(len(tt.data))); err == nil {
+				t.Error("parseVol() succeeded, want an error")
+			}
+		})
+	}
+}
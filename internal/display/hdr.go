@@ -0,0 +1,174 @@
+package display
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+// HDR represents an image of linear colors that may be far brighter than white, such as a photograph of the sky
+// with the sun in it.
+type HDR struct {
+	Width  int
+	Height int
+	Pixels []Color // row by row, starting from the top
+}
+
+// LoadHDR reads an HDR image from a Radiance .hdr file or an OpenEXR .exr file.
+func LoadHDR(path string) (*HDR, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".hdr", ".pic":
+		return parseRadiance(bufio.NewReader(f))
+	case ".exr":
+		return parseEXR(f)
+	default:
+		return nil, fmt.Errorf("unsupported HDR image %q, only .hdr and .exr are supported", path)
+	}
+}
+
+// At returns the color of the pixel at the given surface coordinates, where v is 1 at the top of the image.
+func (h *HDR) At(u float64, v float64, p geometry.Vec) Color {
+	x := clampIndex(int(u*float64(h.Width)), h.Width-1)
+	y := clampIndex(int((1-v)*float64(h.Height)), h.Height-1)
+	return h.Pixels[y*h.Width+x]
+}
+
+// parseRadiance reads an HDR image from the RGBE format of the Radiance renderer.
+//
+// The file starts with a text header, ended by an empty line and followed by the resolution of the image,
+// where only images stored from top to bottom and left to right are supported. Each pixel is stored as red,
+// green and blue mantissas sharing an exponent, and each row either as flat pixels or run-length encoded
+// channel by channel.
+func parseRadiance(r *bufio.Reader) (*HDR, error) {
+	magic, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	if !strings.HasPrefix(magic, "#?") {
+		return nil, errors.New("not a Radiance HDR file")
+	}
+	exposure := 1.0
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("reading header: %w", err)
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		switch {
+		case strings.HasPrefix(line, "FORMAT=") && line != "FORMAT=32-bit_rle_rgbe":
+			return nil, fmt.Errorf("unsupported format %q", strings.TrimPrefix(line, "FORMAT="))
+		case strings.HasPrefix(line, "EXPOSURE="):
+			e, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimPrefix(line, "EXPOSURE=")), 64)
+			if err != nil || e <= 0 {
+				return nil, fmt.Errorf("invalid exposure %q", line)
+			}
+			exposure *= e
+		}
+	}
+
+	resolution, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("reading resolution: %w", err)
+	}
+	var width, height int
+	if _, err := fmt.Sscanf(resolution, "-Y %d +X %d", &height, &width); err != nil {
+		return nil, fmt.Errorf("unsupported resolution %q", strings.TrimSpace(resolution))
+	}
+	if width < 1 || height < 1 || width*height > 1<<28 {
+		return nil, fmt.Errorf("invalid size %dx%d", width, height)
+	}
+
+	h := HDR{Width: width, Height: height, Pixels: make([]Color, width*height)}
+	row := make([]byte, 4*width)
+	for y := 0; y < height; y++ {
+		if err := readRGBERow(r, row); err != nil {
+			return nil, fmt.Errorf("reading row %d: %w", y, err)
+		}
+		for x := 0; x < width; x++ {
+			h.Pixels[y*width+x] = rgbe(row[4*x : 4*x+4]).Scale(1 / exposure)
+		}
+	}
+	return &h, nil
+}
+
+// readRGBERow reads a row of RGBE pixels into row, four bytes per pixel.
+func readRGBERow(r *bufio.Reader, row []byte) error {
+	width := len(row) / 4
+	start, err := r.Peek(4)
+	if err != nil {
+		return err
+	}
+	if width < 8 || width > 0x7fff || start[0] != 2 || start[1] != 2 || start[2]&0x80 != 0 {
+		// The row is stored as flat pixels.
+		_, err := io.ReadFull(r, row)
+		return err
+	}
+	if int(start[2])<<8|int(start[3]) != width {
+		return errors.New("run-length encoded row does not match the width")
+	}
+	if _, err := r.Discard(4); err != nil {
+		return err
+	}
+	// Each channel is stored in turn as runs of a repeated byte or of literal bytes.
+	for c := 0; c < 4; c++ {
+		for x := 0; x < width; {
+			count, err := r.ReadByte()
+			if err != nil {
+				return err
+			}
+			if count > 128 {
+				n := int(count - 128)
+				value, err := r.ReadByte()
+				if err != nil {
+					return err
+				}
+				if x+n > width {
+					return errors.New("run overflows the row")
+				}
+				for i := 0; i < n; i++ {
+					row[4*(x+i)+c] = value
+				}
+				x += n
+				continue
+			}
+			n := int(count)
+			if n == 0 || x+n > width {
+				return errors.New("invalid run length")
+			}
+			for i := 0; i < n; i++ {
+				value, err := r.ReadByte()
+				if err != nil {
+					return err
+				}
+				row[4*(x+i)+c] = value
+			}
+			x += n
+		}
+	}
+	return nil
+}
+
+// rgbe returns the color of a pixel stored as red, green and blue mantissas sharing an exponent.
+func rgbe(p []byte) Color {
+	if p[3] == 0 {
+		return Black
+	}
+	f := math.Ldexp(1, int(p[3])-136)
+	return NewColor((float64(p[0])+0.5)*f, (float64(p[1])+0.5)*f, (float64(p[2])+0.5)*f)
+}
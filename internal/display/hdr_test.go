@@ -0,0 +1,263 @@
+package display
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// hdrWidth is the width of the test images, which are 2 pixels high.
+const hdrWidth = 64
+
+// hdrPixels are the colors of the test images, with the row index in red and the column in green.
+func hdrPixels() []Color {
+	pixels := make([]Color, 2*hdrWidth)
+	for y := 0; y < 2; y++ {
+		for x := 0; x < hdrWidth; x++ {
+			pixels[y*hdrWidth+x] = NewColor(float64(y+1), float64(x)/16, 0.25)
+		}
+	}
+	return pixels
+}
+
+// toRGBE returns the RGBE bytes of twice a color whose channels are multiples of 1/64 below 4, for an
+// exposure of 2.
+func toRGBE(c Color) []byte {
+	// An exponent of 131 gives a step of 1/32 between mantissas, offset by half a step.
+	return []byte{byte(c.Red() * 64), byte(c.Green() * 64), byte(c.Blue() * 64), 131}
+}
+
+func TestParseRadiance(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("#?RADIANCE\nFORMAT=32-bit_rle_rgbe\nEXPOSURE=2\n\n-Y 2 +X 64\n")
+	pixels := hdrPixels()
+	// The first row is run-length encoded, with runs for the constant channels and literals for the others.
+	buf.Write([]byte{2, 2, 0, hdrWidth})
+	for c := 0; c < 4; c++ {
+		first := toRGBE(pixels[0])[c]
+		constant := true
+		for x := 0; x < hdrWidth; x++ {
+			constant = constant && toRGBE(pixels[x])[c] == first
+		}
+		if constant {
+			buf.Write([]byte{128 + hdrWidth, first})
+			continue
+		}
+		buf.WriteByte(hdrWidth)
+		for x := 0; x < hdrWidth; x++ {
+			buf.WriteByte(toRGBE(pixels[x])[c])
+		}
+	}
+	// The second row is stored as flat pixels.
+	for x := 0; x < hdrWidth; x++ {
+		buf.Write(toRGBE(pixels[hdrWidth+x]))
+	}
+
+	h, err := parseRadiance(bufio.NewReader(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkHDR(t, h, 1.0/64)
+}
+
+func TestParseRadianceErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "wrong magic", data: "P6\n8 2\n255\n"},
+		{name: "XYZ format", data: "#?RADIANCE\nFORMAT=32-bit_rle_xyze\n\n-Y 2 +X 8\n"},
+		{name: "flipped", data: "#?RADIANCE\n\n+Y 2 +X 8\n"},
+		{name: "truncated", data: "#?RADIANCE\n\n-Y 2 +X 8\n\x02\x02\x00\x08\x88"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseRadiance(bufio.NewReader(bytes.NewBufferString(tt.data))); err == nil {
+				t.Error("parseRadiance() succeeded, want an error")
+			}
+		})
+	}
+}
+
+// exr returns the bytes of an OpenEXR file of the test pixels, stored with a pixel type and compression.
+func exr(t *testing.T, pixelType int32, compression byte) []byte {
+	var header bytes.Buffer
+	attribute := func(name string, typ string, value []byte) {
+		header.WriteString(name + "\x00" + typ + "\x00")
+		binary.Write(&header, binary.LittleEndian, int32(len(value)))
+		header.Write(value)
+	}
+	var channels bytes.Buffer
+	for _, name := range []string{"B", "G", "R"} {
+		channels.WriteString(name + "\x00")
+		binary.Write(&channels, binary.LittleEndian, []int32{pixelType, 0, 1, 1})
+	}
+	channels.WriteByte(0)
+	attribute("channels", "chlist", channels.Bytes())
+	attribute("compression", "compression", []byte{compression})
+	var window bytes.Buffer
+	binary.Write(&window, binary.LittleEndian, []int32{10, 20, 10 + hdrWidth - 1, 21})
+	attribute("dataWindow", "box2i", window.Bytes())
+	header.WriteByte(0)
+
+	// The lines of each chunk, with the values of each channel in turn.
+	linesPerChunk := 1
+	if compression == exrZIPCompression {
+		linesPerChunk = 16
+	}
+	pixels := hdrPixels()
+	var chunks [][]byte
+	for y := 0; y < 2; y += linesPerChunk {
+		var raw bytes.Buffer
+		for line := y; line < 2 && line < y+linesPerChunk; line++ {
+			for _, c := range []func(Color) float64{Color.Blue, Color.Green, Color.Red} {
+				for x := 0; x < hdrWidth; x++ {
+					v := float32(c(pixels[line*hdrWidth+x]))
+					if pixelType == 1 {
+						binary.Write(&raw, binary.LittleEndian, floatToHalf(v))
+					} else {
+						binary.Write(&raw, binary.LittleEndian, v)
+					}
+				}
+			}
+		}
+		chunks = append(chunks, exrCompress(t, raw.Bytes(), compression))
+	}
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, []int32{exrMagic, 2})
+	buf.Write(header.Bytes())
+	offset := buf.Len() + 8*len(chunks)
+	for _, chunk := range chunks {
+		binary.Write(&buf, binary.LittleEndian, uint64(offset))
+		offset += 8 + len(chunk)
+	}
+	for i, chunk := range chunks {
+		binary.Write(&buf, binary.LittleEndian, []int32{int32(20 + i*linesPerChunk), int32(len(chunk))})
+		buf.Write(chunk)
+	}
+	return buf.Bytes()
+}
+
+// exrCompress compresses the pixels of a chunk, reversing exrUncompress.
+func exrCompress(t *testing.T, raw []byte, compression byte) []byte {
+	if compression == exrNoCompression {
+		return raw
+	}
+	// Split the bytes into two halves and store the differences between successive bytes.
+	split := make([]byte, 0, len(raw))
+	for i := 0; i < len(raw); i += 2 {
+		split = append(split, raw[i])
+	}
+	for i := 1; i < len(raw); i += 2 {
+		split = append(split, raw[i])
+	}
+	deltas := make([]byte, len(split))
+	deltas[0] = split[0]
+	for i := 1; i < len(split); i++ {
+		deltas[i] = split[i] - split[i-1] + 128
+	}
+
+	var buf bytes.Buffer
+	if compression == exrRLECompression {
+		// Runs of repeated bytes, with the other bytes stored one at a time as literals.
+		for i := 0; i < len(deltas); {
+			n := 1
+			for i+n < len(deltas) && deltas[i+n] == deltas[i] && n < 128 {
+				n++
+			}
+			if n > 1 {
+				buf.Write([]byte{byte(n - 1), deltas[i]})
+			} else {
+				buf.Write([]byte{0xff, deltas[i]})
+			}
+			i += n
+		}
+	} else {
+		z := zlib.NewWriter(&buf)
+		if _, err := z.Write(deltas); err != nil {
+			t.Fatal(err)
+		}
+		if err := z.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if buf.Len() >= len(raw) {
+		t.Fatalf("compressed chunk of %d bytes does not shrink, would be stored as is", len(raw))
+	}
+	return buf.Bytes()
+}
+
+// floatToHalf returns the 16-bit float of a value exactly representable as one.
+func floatToHalf(v float32) uint16 {
+	if v == 0 {
+		return 0
+	}
+	bits := math.Float32bits(v)
+	exponent := int(bits>>23&0xff) - 127 + 15
+	return uint16(exponent)<<10 | uint16(bits>>13&0x3ff)
+}
+
+func TestParseEXR(t *testing.T) {
+	tests := []struct {
+		name        string
+		pixelType   int32
+		compression byte
+	}{
+		{name: "uncompressed floats", pixelType: 2, compression: exrNoCompression},
+		{name: "uncompressed halves", pixelType: 1, compression: exrNoCompression},
+		{name: "RLE halves", pixelType: 1, compression: exrRLECompression},
+		{name: "ZIPS floats", pixelType: 2, compression: exrZIPSCompression},
+		{name: "ZIP halves", pixelType: 1, compression: exrZIPCompression},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := parseEXR(bytes.NewReader(exr(t, tt.pixelType, tt.compression)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkHDR(t, h, 1e-6)
+		})
+	}
+}
+
+func TestParseEXRErrors(t *testing.T) {
+	valid := exr(t, 2, exrNoCompression)
+	tiled := append([]byte{}, valid...)
+	tiled[5] |= 0x02
+	piz := exr(t, 2, exrNoCompression)
+	piz[bytes.Index(piz, []byte("compression\x00compression\x00"))+28] = 4
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "wrong magic", data: append([]byte{0, 0, 0, 0}, valid[4:]...)},
+		{name: "tiled", data: tiled},
+		{name: "PIZ compression", data: piz},
+		{name: "truncated", data: valid[:len(valid)-4]},
+		{name: "empty", data: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseEXR(bytes.NewReader(tt.data)); err == nil {
+				t.Error("parseEXR() succeeded, want an error")
+			}
+		})
+	}
+}
+
+// checkHDR checks that an image holds the test pixels.
+func checkHDR(t *testing.T, h *HDR, tolerance float64) {
+	t.Helper()
+	if h.Width != hdrWidth || h.Height != 2 {
+		t.Fatalf("size = %dx%d, want %dx2", h.Width, h.Height, hdrWidth)
+	}
+	for i, want := range hdrPixels() {
+		if got := h.Pixels[i]; got.Sub(want.Vec).Len() > tolerance {
+			t.Errorf("pixel %d = %v, want %v", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package display
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+// Heightfield represents terrain described by a grid of heights spread evenly over the XZ plane.
+//
+// The terrain fills the box from Min to Max: the first sample of the grid sits at Min.X, Min.Z, the last one
+// at Max.X, Max.Z, and heights from 0 to 1 are mapped from Min.Y to Max.Y. Each cell of the grid is split
+// into two triangles whose normals are interpolated from the slope of the grid around their corners.
+//
+// The UV coordinates of the terrain match the layout of an image used to build it, so that a colour image
+// of the same area can be wrapped over it with the Image texture.
+type Heightfield struct {
+	Min      geometry.Vec
+	Max      geometry.Vec
+	Material Material
+	nx       int             // number of samples along X
+	nz       int             // number of samples along Z
+	heights  []float64       // height of each sample in world coordinates, row by row along Z
+	normals  []geometry.Unit // normal at each sample
+	levels   []heightLevel   // min/max mip hierarchy, from individual cells up to the whole grid
+}
+
+// heightLevel holds the lowest and highest point within each block of cells for a level of the mip hierarchy.
+// Each block in a level covers 2x2 blocks of the level below it.
+type heightLevel struct {
+	w   int
+	h   int
+	min []float64
+	max []float64
+}
+
+// NewHeightfield returns a new Heightfield from nx by nz heights between 0 and 1, stored row by row along Z.
+func NewHeightfield(nx int, nz int, heights []float64, min geometry.Vec, max geometry.Vec, material Material) *Heightfield {
+	if nx < 2 || nz < 2 || len(heights) != nx*nz {
+		panic("heightfield needs at least 2x2 heights")
+	}
+	hf := Heightfield{
+		Min:      min,
+		Max:      max,
+		Material: material,
+		nx:       nx,
+		nz:       nz,
+		heights:  make([]float64, len(heights)),
+		normals:  make([]geometry.Unit, len(heights)),
+	}
+	for k, h := range heights {
+		hf.heights[k] = min.Y + h*(max.Y-min.Y)
+	}
+	hf.computeNormals()
+	hf.buildLevels()
+	return &hf
+}
+
+// NewImageHeightfield returns a new Heightfield using the brightness of each pixel of an Image as its height.
+func NewImageHeightfield(img *Image, min geometry.Vec, max geometry.Vec, material Material) *Heightfield {
+	bounds := img.Data.Bounds()
+	nx, nz := bounds.Dx(), bounds.Dy()
+	heights := make([]float64, 0, nx*nz)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			gray := color.Gray16Model.Convert(img.Data.At(x, y)).(color.Gray16)
+			heights = append(heights, float64(gray.Y)/65535)
+		}
+	}
+	return NewHeightfield(nx, nz, heights, min, max, material)
+}
+
+// NewPerlinHeightfield returns a new Heightfield of nx by nz heights sampled from the turbulence of a Perlin noise.
+// Scale sets the frequency of the noise over the terrain, and the heights are stretched to fill the box.
+func NewPerlinHeightfield(per Perlin, nx int, nz int, scale float64, min geometry.Vec, max geometry.Vec, material Material) *Heightfield {
+	heights := make([]float64, nx*nz)
+	low, high := math.MaxFloat64, -math.MaxFloat64
+	for j := 0; j < nz; j++ {
+		for i := 0; i < nx; i++ {
+			p := geometry.NewVec(float64(i)/float64(nx-1), 0, float64(j)/float64(nz-1)).Scale(scale)
+			h := per.turbulence(p, 7)
+			heights[j*nx+i] = h
+			low, high = math.Min(low, h), math.Max(high, h)
+		}
+	}
+	if high > low {
+		for k := range heights {
+			heights[k] = (heights[k] - low) / (high - low)
+		}
+	}
+	return NewHeightfield(nx, nz, heights, min, max, material)
+}
+
+// cellSize returns the size of a cell of the grid along X and Z.
+func (hf *Heightfield) cellSize() (float64, float64) {
+	return (hf.Max.X - hf.Min.X) / float64(hf.nx-1), (hf.Max.Z - hf.Min.Z) / float64(hf.nz-1)
+}
+
+// height returns the height of the sample at column i and row j, clamped to the edges of the grid.
+func (hf *Heightfield) height(i int, j int) float64 {
+	i = clampIndex(i, hf.nx-1)
+	j = clampIndex(j, hf.nz-1)
+	return hf.heights[j*hf.nx+i]
+}
+
+// vertex returns the position of the sample at column i and row j.
+func (hf *Heightfield) vertex(i int, j int) geometry.Vec {
+	dx, dz := hf.cellSize()
+	return geometry.NewVec(hf.Min.X+float64(i)*dx, hf.height(i, j), hf.Min.Z+float64(j)*dz)
+}
+
+// computeNormals estimates the normal at each sample from the slope of the heights around it.
+func (hf *Heightfield) computeNormals() {
+	dx, dz := hf.cellSize()
+	for j := 0; j < hf.nz; j++ {
+		for i := 0; i < hf.nx; i++ {
+			slopeX := (hf.height(i+1, j) - hf.height(i-1, j)) / (2 * dx)
+			slopeZ := (hf.height(i, j+1) - hf.height(i, j-1)) / (2 * dz)
+			hf.normals[j*hf.nx+i] = geometry.NewVec(-slopeX, 1, -slopeZ).ToUnit()
+		}
+	}
+}
+
+// buildLevels builds the min/max mip hierarchy, starting from one block per cell of the grid
+// and halving the number of blocks along each axis until a single block covers the whole grid.
+func (hf *Heightfield) buildLevels() {
+	w, h := hf.nx-1, hf.nz-1
+	base := heightLevel{w: w, h: h, min: make([]float64, w*h), max: make([]float64, w*h)}
+	for j := 0; j < h; j++ {
+		for i := 0; i < w; i++ {
+			corners := []float64{hf.height(i, j), hf.height(i+1, j), hf.height(i, j+1), hf.height(i+1, j+1)}
+			base.min[j*w+i], base.max[j*w+i] = corners[0], corners[0]
+			for _, c := range corners[1:] {
+				base.min[j*w+i] = math.Min(base.min[j*w+i], c)
+				base.max[j*w+i] = math.Max(base.max[j*w+i], c)
+			}
+		}
+	}
+	hf.levels = []heightLevel{base}
+	for below := base; below.w > 1 || below.h > 1; {
+		w, h := (below.w+1)/2, (below.h+1)/2
+		level := heightLevel{w: w, h: h, min: make([]float64, w*h), max: make([]float64, w*h)}
+		for j := 0; j < h; j++ {
+			for i := 0; i < w; i++ {
+				low, high := math.MaxFloat64, -math.MaxFloat64
+				for dj := 0; dj < 2; dj++ {
+					for di := 0; di < 2; di++ {
+						ci, cj := 2*i+di, 2*j+dj
+						if ci < below.w && cj < below.h {
+							low = math.Min(low, below.min[cj*below.w+ci])
+							high = math.Max(high, below.max[cj*below.w+ci])
+						}
+					}
+				}
+				level.min[j*w+i], level.max[j*w+i] = low, high
+			}
+		}
+		hf.levels = append(hf.levels, level)
+		below = level
+	}
+}
+
+// Hit finds the first intersection between a ray and the terrain.
+func (hf *Heightfield) Hit(r *geometry.Ray, tMin float64, tMax float64) (bool, *HitRecord) {
+	rec := hf.traverse(r, len(hf.levels)-1, 0, 0, tMin, tMax)
+	if rec == nil {
+		return false, nil
+	}
+	return true, rec
+}
+
+// traverse descends the mip hierarchy from block i, j of the given level, skipping every block whose
+// bounding box the ray misses, down to the cells whose triangles are intersected with the ray.
+func (hf *Heightfield) traverse(r *geometry.Ray, level int, i int, j int, tMin float64, tMax float64) *HitRecord {
+	lvl := hf.levels[level]
+	span := 1 << level
+	x0, z0 := i*span, j*span
+	x1, z1 := clampIndex(x0+span, hf.nx-1), clampIndex(z0+span, hf.nz-1)
+	lo, hi := hf.vertex(x0, z0), hf.vertex(x1, z1)
+	box := NewAABB(
+		geometry.NewVec(lo.X, lvl.min[j*lvl.w+i]-bias, lo.Z),
+		geometry.NewVec(hi.X, lvl.max[j*lvl.w+i]+bias, hi.Z),
+	)
+	if hit, _, _ := box.Clip(r, tMin, tMax); !hit {
+		return nil
+	}
+	if level == 0 {
+		return hf.hitCell(r, i, j, tMin, tMax)
+	}
+
+	// Visit the children closest to the origin of the ray first so that the furthest ones can be
+	// skipped once a closer hit has been found.
+	below := hf.levels[level-1]
+	di0, dj0 := 0, 0
+	if r.Direction.X < 0 {
+		di0 = 1
+	}
+	if r.Direction.Z < 0 {
+		dj0 = 1
+	}
+	var closest *HitRecord
+	for _, d := range [4][2]int{{di0, dj0}, {1 - di0, dj0}, {di0, 1 - dj0}, {1 - di0, 1 - dj0}} {
+		ci, cj := 2*i+d[0], 2*j+d[1]
+		if ci >= below.w || cj >= below.h {
+			continue
+		}
+		if rec := hf.traverse(r, level-1, ci, cj, tMin, tMax); rec != nil {
+			closest, tMax = rec, rec.t
+		}
+	}
+	return closest
+}
+
+// hitCell intersects a ray with the two triangles making up the cell at column i and row j.
+func (hf *Heightfield) hitCell(r *geometry.Ray, i int, j int, tMin float64, tMax float64) *HitRecord {
+	corners := [4][2]int{{i, j}, {i + 1, j}, {i + 1, j + 1}, {i, j + 1}}
+	var closest *HitRecord
+	for _, tri := range [2][3]int{{0, 1, 2}, {0, 2, 3}} {
+		a, b, c := corners[tri[0]], corners[tri[1]], corners[tri[2]]
+		t, beta, gamma, hit := intersectTriangle(r, hf.vertex(a[0], a[1]), hf.vertex(b[0], b[1]), hf.vertex(c[0], c[1]))
+		if !hit || t <= tMin || t >= tMax {
+			continue
+		}
+		alpha := 1 - beta - gamma
+		normal := hf.normals[a[1]*hf.nx+a[0]].Scale(alpha).
+			Add(hf.normals[b[1]*hf.nx+b[0]].Scale(beta)).
+			Add(hf.normals[c[1]*hf.nx+c[0]].Scale(gamma))
+		p := r.At(t)
+		closest = &HitRecord{
+			t:        t,
+			p:        p,
+			normal:   normal.ToUnit(),
+			Material: hf.Material,
+			u:        (p.X - hf.Min.X) / (hf.Max.X - hf.Min.X),
+			v:        1 - (p.Z-hf.Min.Z)/(hf.Max.Z-hf.Min.Z),
+			dpdu:     geometry.NewVec(hf.Max.X-hf.Min.X, 0, 0),
+			dpdv:     geometry.NewVec(0, 0, hf.Min.Z-hf.Max.Z),
+		}
+		tMax = t
+	}
+	return closest
+}
+
+// intersectTriangle finds the intersection between a ray and the triangle a, b, c with the Möller-Trumbore
+// algorithm, returning the distance along the ray and the barycentric coordinates of b and c at the hit.
+func intersectTriangle(r *geometry.Ray, a geometry.Vec, b geometry.Vec, c geometry.Vec) (float64, float64, float64, bool) {
+	edge1 := b.Sub(a)
+	edge2 := c.Sub(a)
+	pvec := r.Direction.Cross(edge2)
+	det := edge1.Dot(pvec)
+	if math.Abs(det) < 1e-12 {
+		return 0, 0, 0, false
+	}
+	invDet := 1 / det
+	tvec := r.Origin.Sub(a)
+	beta := tvec.Dot(pvec) * invDet
+	if beta < 0 || beta > 1 {
+		return 0, 0, 0, false
+	}
+	qvec := tvec.Cross(edge1)
+	gamma := r.Direction.Vec.Dot(qvec) * invDet
+	if gamma < 0 || beta+gamma > 1 {
+		return 0, 0, 0, false
+	}
+	return edge2.Dot(qvec) * invDet, beta, gamma, true
+}
+
+// Box returns the bounding box of the terrain.
+func (hf *Heightfield) Box(t0 float64, t1 float64) *AABB {
+	top := hf.levels[len(hf.levels)-1]
+	return NewAABB(
+		geometry.NewVec(hf.Min.X, top.min[0]-bias, hf.Min.Z),
+		geometry.NewVec(hf.Max.X, top.max[0]+bias, hf.Max.Z),
+	)
+}
+
+// clampIndex restricts the index i to the range [0, last].
+func clampIndex(i int, last int) int {
+	if i < 0 {
+		return 0
+	}
+	if i > last {
+		return last
+	}
+	return i
+}
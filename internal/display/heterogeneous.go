@@ -0,0 +1,109 @@
+package display
+
+import (
+	"math"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+// maxTrackingSteps is the number of tentative collisions after which tracking through a HeterogeneousVolume gives up.
+const maxTrackingSteps = 1 << 16
+
+// DensityField represents a density varying through space, such as that of a cloud or of smoke.
+type DensityField interface {
+	// Density returns the density at the point p.
+	Density(p geometry.Vec) float64
+	// Majorant returns a bound that the density never goes above.
+	Majorant() float64
+}
+
+// PerlinDensity represents a DensityField following the turbulence of a Perlin noise, between 0 and 1.
+// Scale sets the frequency of the noise, so that larger scales give smaller puffs.
+type PerlinDensity struct {
+	per   Perlin
+	Scale float64
+}
+
+// NewPerlinDensity returns a new PerlinDensity with a given scale.
+func NewPerlinDensity(rnd geometry.Rnd, scale float64) PerlinDensity {
+	return PerlinDensity{per: NewPerlin(rnd), Scale: scale}
+}
+
+// Density returns the turbulence of the noise at the point p.
+func (d PerlinDensity) Density(p geometry.Vec) float64 {
+	return clamp(d.per.turbulence(p.Scale(d.Scale), 7), 0, 1)
+}
+
+// Majorant returns 1.
+func (d PerlinDensity) Majorant() float64 {
+	return 1
+}
+
+// HeterogeneousVolume represents a closed Boundary filled with a participating medium whose density follows
+// a DensityField, scaled by Density, such as a cloud, smoke or an explosion.
+//
+// Unlike a Volume, the density changes along rays, so scattering events are found with delta tracking: the
+// medium is padded with fictitious matter up to the majorant of the field, which makes its density constant,
+// and each tentative collision is kept with a chance equal to the share of real matter at that point.
+type HeterogeneousVolume struct {
+	Boundary HitBoxer
+	Field    DensityField
+	Density  float64
+	Phase    Material
+}
+
+// NewHeterogeneousVolume returns a new HeterogeneousVolume.
+func NewHeterogeneousVolume(boundary HitBoxer, field DensityField, density float64, phase Material) *HeterogeneousVolume {
+	return &HeterogeneousVolume{Boundary: boundary, Field: field, Density: density, Phase: phase}
+}
+
+// NewGridVolume returns a new HeterogeneousVolume filling the box of a Grid with its densities.
+func NewGridVolume(grid *Grid, density float64, phase Material) *HeterogeneousVolume {
+	return NewHeterogeneousVolume(NewBlock(grid.Min, grid.Max, nil), grid, density, phase)
+}
+
+// Hit finds the first scattering event along a ray within the volume, using delta tracking.
+func (h *HeterogeneousVolume) Hit(ray *geometry.Ray, dMin float64, dMax float64) (bool, *HitRecord) {
+	d, d1, ok := span(h.Boundary, ray, dMin, dMax)
+	majorant := h.Density * h.Field.Majorant()
+	if !ok || majorant <= 0 {
+		return false, nil
+	}
+	for i := 0; i < maxTrackingSteps; i++ {
+		d -= math.Log(1-ray.Rnd.Float64()) / majorant
+		if d >= d1 {
+			return false, nil
+		}
+		if ray.Rnd.Float64()*majorant < h.Density*h.Field.Density(ray.At(d)) {
+			return true, scatteringEvent(ray, d, h.Phase)
+		}
+	}
+	return false, nil
+}
+
+// Box returns the bounding box of the boundary.
+func (h *HeterogeneousVolume) Box(t0 float64, t1 float64) *AABB {
+	return h.Boundary.Box(t0, t1)
+}
+
+// Transmittance returns an estimate of the fraction of light making it along a ray through the volume from
+// dMin to dMax without being scattered or absorbed, using ratio tracking.
+//
+// Rather than stopping at the first collision like delta tracking, each tentative collision lowers the
+// estimate by the share of real matter at that point, which gives far less noisy shadows through the volume.
+func (h *HeterogeneousVolume) Transmittance(ray *geometry.Ray, dMin float64, dMax float64) float64 {
+	d, d1, ok := span(h.Boundary, ray, dMin, dMax)
+	majorant := h.Density * h.Field.Majorant()
+	if !ok || majorant <= 0 {
+		return 1
+	}
+	transmittance := 1.0
+	for i := 0; i < maxTrackingSteps && transmittance > 0; i++ {
+		d -= math.Log(1-ray.Rnd.Float64()) / majorant
+		if d >= d1 {
+			break
+		}
+		transmittance *= 1 - h.Density*h.Field.Density(ray.At(d))/majorant
+	}
+	return transmittance
+}
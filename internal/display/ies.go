@@ -0,0 +1,227 @@
+package display
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+// IESProfile represents the light emitted in each direction by a light fixture, as measured by its
+// manufacturer and stored in an IES LM-63 file.
+//
+// The profile gives the luminous intensity in candelas at vertical angles from the nadir of the fixture,
+// straight below it, to its zenith, and at horizontal angles turning around it. Only the type C photometry
+// used for architectural and indoor fixtures is supported. Profiles measured over part of the horizontal
+// angles are symmetric: a single angle for fixtures lighting the same all around, up to 90 degrees for
+// fixtures symmetric in each quadrant and up to 180 degrees for fixtures symmetric across a plane.
+type IESProfile struct {
+	Keywords   map[string]string // such as MANUFAC or LUMINAIRE, without brackets
+	Lumens     float64           // the rated light of the lamps, or -1 when the candelas are absolute
+	Watts      float64           // the power drawn by the fixture
+	Vertical   []float64         // the vertical angles in degrees, from 0 at the nadir
+	Horizontal []float64         // the horizontal angles in degrees
+	Candela    [][]float64       // the intensity at each vertical angle, for each horizontal angle in turn
+	flux       float64           // the light emitted over the whole sphere
+}
+
+// LoadIES reads an IESProfile from an IES LM-63 file.
+func LoadIES(path string) (*IESProfile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseIES(f)
+}
+
+// Intensity returns the luminous intensity in candelas at a vertical angle from the nadir and a horizontal
+// angle in degrees, interpolated between the angles of the profile.
+func (p *IESProfile) Intensity(vertical float64, horizontal float64) float64 {
+	if vertical < p.Vertical[0] || vertical > p.Vertical[len(p.Vertical)-1] {
+		return 0
+	}
+	// Fold the horizontal angle into the angles covered by the profile.
+	horizontal = math.Mod(horizontal, 360)
+	if horizontal < 0 {
+		horizontal += 360
+	}
+	switch last := p.Horizontal[len(p.Horizontal)-1]; {
+	case last == 90:
+		if horizontal > 180 {
+			horizontal = 360 - horizontal
+		}
+		if horizontal > 90 {
+			horizontal = 180 - horizontal
+		}
+	case last == 180 && horizontal > 180:
+		horizontal = 360 - horizontal
+	}
+	i, s := iesInterval(p.Vertical, vertical)
+	j, t := iesInterval(p.Horizontal, horizontal)
+	at := func(j int) float64 {
+		column := p.Candela[j]
+		if i+1 >= len(column) {
+			return column[i]
+		}
+		return lerp(column[i], column[i+1], s)
+	}
+	if j+1 >= len(p.Candela) {
+		return at(j)
+	}
+	return lerp(at(j), at(j+1), t)
+}
+
+// Flux returns the light emitted by the fixture over the whole sphere, in lumens.
+func (p *IESProfile) Flux() float64 {
+	return p.flux
+}
+
+// relative returns the intensity of the profile in the direction dir, relative to its average over the
+// sphere, with the nadir along the W axis of a basis and the horizontal angles turning from U towards V.
+func (p *IESProfile) relative(b geometry.Basis, dir geometry.Vec) float64 {
+	if p.flux <= 0 {
+		return 0
+	}
+	local := b.Local(dir)
+	vertical := math.Acos(clamp(local.Z/local.Len(), -1, 1)) * 180 / math.Pi
+	horizontal := math.Atan2(local.Y, local.X) * 180 / math.Pi
+	return p.Intensity(vertical, horizontal) * 4 * math.Pi / p.flux
+}
+
+// iesInterval returns the index of the angle at or below x among ascending angles, and how far x lies
+// towards the next angle.
+func iesInterval(angles []float64, x float64) (int, float64) {
+	i := clampIndex(sort.SearchFloat64s(angles, x)-1, len(angles)-1)
+	if i+1 >= len(angles) || x <= angles[i] {
+		return i, 0
+	}
+	return i, clamp((x-angles[i])/(angles[i+1]-angles[i]), 0, 1)
+}
+
+// parseIES reads an IESProfile from the LM-63 format.
+//
+// The file starts with lines of text, usually keywords in brackets, up to the TILT line. The rest of the
+// file is a list of numbers separated by spaces, commas or line breaks: the tilt of the lamps when it is
+// included in the file, the description of the fixture, the angles and finally the candelas. The tilt of
+// the lamps only matters for fixtures mounted at an angle and is ignored.
+func parseIES(r io.Reader) (*IESProfile, error) {
+	scanner := bufio.NewScanner(r)
+	p := IESProfile{Keywords: map[string]string{}}
+	tilt := ""
+	for tilt == "" {
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return nil, fmt.Errorf("reading header: %w", err)
+			}
+			return nil, errors.New("missing TILT line")
+		}
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "TILT="):
+			tilt = strings.TrimPrefix(line, "TILT=")
+		case strings.HasPrefix(line, "["):
+			if end := strings.Index(line, "]"); end > 0 {
+				p.Keywords[line[1:end]] = strings.TrimSpace(line[end+1:])
+			}
+		}
+	}
+
+	var numbers []float64
+	for scanner.Scan() {
+		for _, field := range strings.FieldsFunc(scanner.Text(), func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\r'
+		}) {
+			n, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", field)
+			}
+			numbers = append(numbers, n)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading data: %w", err)
+	}
+	next := func(n int) ([]float64, error) {
+		if n < 0 || n > len(numbers) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		values := numbers[:n]
+		numbers = numbers[n:]
+		return values, nil
+	}
+
+	if tilt == "INCLUDE" {
+		// The geometry of the lamps, then pairs of angles and multipliers.
+		header, err := next(2)
+		if err != nil {
+			return nil, fmt.Errorf("reading tilt: %w", err)
+		}
+		if _, err := next(2 * int(header[1])); err != nil {
+			return nil, fmt.Errorf("reading tilt: %w", err)
+		}
+	}
+
+	description, err := next(13)
+	if err != nil {
+		return nil, fmt.Errorf("reading description: %w", err)
+	}
+	lamps, lumens, multiplier := description[0], description[1], description[2]
+	verticals, horizontals, photometry := int(description[3]), int(description[4]), description[5]
+	ballast := description[10]
+	p.Watts = description[12]
+	p.Lumens = lumens * lamps
+	if lumens < 0 {
+		p.Lumens = -1
+	}
+	if photometry != 1 {
+		return nil, fmt.Errorf("unsupported photometric type %v, only type C is supported", photometry)
+	}
+	if verticals < 1 || horizontals < 1 || verticals*horizontals > 1<<20 {
+		return nil, fmt.Errorf("invalid number of angles %dx%d", verticals, horizontals)
+	}
+	if p.Vertical, err = next(verticals); err != nil {
+		return nil, fmt.Errorf("reading vertical angles: %w", err)
+	}
+	if p.Horizontal, err = next(horizontals); err != nil {
+		return nil, fmt.Errorf("reading horizontal angles: %w", err)
+	}
+	for _, angles := range [][]float64{p.Vertical, p.Horizontal} {
+		if !sort.Float64sAreSorted(angles) {
+			return nil, errors.New("angles are not in ascending order")
+		}
+	}
+	if first, last := p.Vertical[0], p.Vertical[verticals-1]; first < 0 || last > 180 {
+		return nil, fmt.Errorf("vertical angles from %v to %v lie outside 0 to 180 degrees", first, last)
+	}
+	if first, last := p.Horizontal[0], p.Horizontal[horizontals-1]; first != 0 || (last != 0 && last != 90 && last != 180 && last != 360) {
+		return nil, fmt.Errorf("unsupported horizontal angles from %v to %v", first, last)
+	}
+	p.Candela = make([][]float64, horizontals)
+	for j := range p.Candela {
+		if p.Candela[j], err = next(verticals); err != nil {
+			return nil, fmt.Errorf("reading candelas: %w", err)
+		}
+		for i := range p.Candela[j] {
+			p.Candela[j][i] *= multiplier * ballast
+		}
+	}
+
+	// The flux is the intensity integrated over the sphere.
+	const steps = 180
+	for i := 0; i < steps; i++ {
+		vertical := (float64(i) + 0.5) * 180 / steps
+		area := math.Sin(radians(vertical)) * (math.Pi / steps) * (2 * math.Pi / (2 * steps))
+		for j := 0; j < 2*steps; j++ {
+			p.flux += p.Intensity(vertical, (float64(j)+0.5)*360/(2*steps)) * area
+		}
+	}
+	return &p, nil
+}
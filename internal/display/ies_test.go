@@ -0,0 +1,179 @@
+package display
+
+import (
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+func TestLoadIES(t *testing.T) {
+	type intensity struct {
+		vertical, horizontal, want float64
+	}
+	tests := []struct {
+		file      string
+		luminaire string
+		lumens    float64
+		watts     float64
+		flux      float64
+		intensity []intensity
+	}{
+		{
+			file:      "testdata/isotropic.ies",
+			luminaire: "Bare bulb emitting equally in every direction",
+			lumens:    1000,
+			watts:     10,
+			flux:      1000,
+			intensity: []intensity{{0, 0, 79.5775}, {30, 77, 79.5775}, {180, 300, 79.5775}},
+		},
+		{
+			// The downlight of the stage world is checked in the assets the renderer loads it from.
+			file:      "../../assets/downlight.ies",
+			luminaire: "Recessed downlight with a cosine distribution",
+			lumens:    1500,
+			watts:     18,
+			flux:      1000 * math.Pi,
+			intensity: []intensity{{0, 0, 1000}, {60, 200, 500}, {52.5, 0, 603.553}, {90, 0, 0}, {120, 45, 0}},
+		},
+		{
+			file:      "testdata/wallwash.ies",
+			luminaire: "Asymmetric wall washer, symmetric in each quadrant",
+			lumens:    -1,
+			watts:     24,
+			flux:      2554.7,
+			intensity: []intensity{
+				{0, 0, 400}, {45, 45, 350}, {45, 135, 350}, {45, 270, 450}, {45, 22.5, 325}, {67.5, 90, 375}, {180, 90, 0},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(filepath.Base(tt.file), func(t *testing.T) {
+			p, err := LoadIES(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := p.Keywords["LUMINAIRE"]; got != tt.luminaire {
+				t.Errorf("LUMINAIRE = %q, want %q", got, tt.luminaire)
+			}
+			if p.Lumens != tt.lumens || p.Watts != tt.watts {
+				t.Errorf("Lumens, Watts = %v, %v, want %v, %v", p.Lumens, p.Watts, tt.lumens, tt.watts)
+			}
+			// The linear interpolation between the angles differs a little from the measured curves.
+			if got := p.Flux(); math.Abs(got-tt.flux)/tt.flux > 0.01 {
+				t.Errorf("Flux() = %v, want %v", got, tt.flux)
+			}
+			for _, i := range tt.intensity {
+				if got := p.Intensity(i.vertical, i.horizontal); math.Abs(got-i.want) > 1e-3 {
+					t.Errorf("Intensity(%v, %v) = %v, want %v", i.vertical, i.horizontal, got, i.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseIESErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "missing tilt", data: "IESNA:LM-63-2002\n[TEST] no tilt\n"},
+		{name: "type B", data: "TILT=NONE\n1 1000 1 2 1 2 2 0 0 0\n1 1 10\n0 90\n0\n100 100\n"},
+		{name: "truncated", data: "TILT=NONE\n1 1000 1 2 1 1 2 0 0 0\n1 1 10\n0 90\n0\n100\n"},
+		{name: "truncated tilt", data: "TILT=INCLUDE\n1\n3\n0 45 90\n1 1\n"},
+		{name: "descending angles", data: "TILT=NONE\n1 1000 1 2 1 1 2 0 0 0\n1 1 10\n90 0\n0\n100 100\n"},
+		{name: "three quarters", data: "TILT=NONE\n1 1000 1 1 2 1 2 0 0 0\n1 1 10\n0\n0 270\n100 100\n"},
+		{name: "not a number", data: "TILT=NONE\n1 1000 1 2 1 1 2 0 0 zero\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseIES(strings.NewReader(tt.data)); err == nil {
+				t.Error("parseIES() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestIESLights(t *testing.T) {
+	downlight, err := LoadIES("../../assets/downlight.ies")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallwash, err := LoadIES("testdata/wallwash.ies")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The average intensity of the downlight is a quarter of its intensity at the nadir.
+	peak := 1000 * 4 * math.Pi / downlight.Flux()
+
+	point := NewPointLight(geometry.Vec{}, White, 100)
+	point.Profile = downlight
+	spot := NewSpotLight(geometry.Vec{}, geometry.NewVec(1, 0, 0), White, 100, 60, 0)
+	spot.Profile = downlight
+	tilted := NewPointLight(geometry.Vec{}, White, 100)
+	tilted.Profile = wallwash
+	tilted.Down = geometry.NewUnit(0, 0, -1)
+	tests := []struct {
+		name  string
+		light LightSource
+		p     geometry.Vec
+		want  float64 // relative to the light without a profile
+	}{
+		{name: "point below", light: point, p: geometry.NewVec(0, -1, 0), want: peak},
+		{name: "point aside", light: point, p: geometry.NewVec(1, -1, 0), want: peak * math.Cos(math.Pi/4)},
+		{name: "point above", light: point, p: geometry.NewVec(0, 1, 0), want: 0},
+		{name: "spot along its axis", light: spot, p: geometry.NewVec(1, 0, 0), want: peak},
+		{name: "spot at 30 degrees", light: spot, p: geometry.NewVec(math.Cos(math.Pi/6), 0, math.Sin(math.Pi/6)), want: peak * math.Cos(math.Pi/6)},
+		{name: "tilted at the nadir", light: tilted, p: geometry.NewVec(0, 0, -1), want: 400 * 4 * math.Pi / wallwash.Flux()},
+		{name: "tilted towards +X", light: tilted, p: geometry.NewVec(1, 0, -1), want: 300 * 4 * math.Pi / wallwash.Flux()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plain := tt.light
+			switch l := plain.(type) {
+			case PointLight:
+				l.Profile = nil
+				plain = l
+			case SpotLight:
+				l.Profile = nil
+				plain = l
+			}
+			got := tt.light.Sample(tt.p, nil).Light.Red()
+			want := plain.Sample(tt.p, nil).Light.Red() * tt.want
+			if math.Abs(got-want) > 1e-2*math.Max(want, 1e-3) {
+				t.Errorf("light = %v, want %v", got, want)
+			}
+		})
+	}
+
+	// The intensity emitted by a surface, its light times the cosine to the normal, follows the profile on
+	// either side of a two-sided surface, with the same power as without a profile.
+	e := NewEmissive(nil, NewSolid(White), 2)
+	e.Profile = downlight
+	e.TwoSided = true
+	rec := &HitRecord{normal: geometry.NewUnit(0, 1, 0), dpdu: geometry.NewVec(1, 0, 0)}
+	for _, side := range []float64{1, -1} {
+		emitted := func(theta float64, phi float64) float64 {
+			out := geometry.NewVec(math.Sin(theta)*math.Cos(phi), side*math.Cos(theta), math.Sin(theta)*math.Sin(phi))
+			r := geometry.NewRay(geometry.Vec{}, out.Inv().ToUnit(), 0, nil)
+			return e.Emit(r, rec).Red() * math.Cos(theta)
+		}
+		nadir := emitted(0, 0)
+		for _, degrees := range []float64{30, 45, 60, 75} {
+			if got, want := emitted(radians(degrees), 1)/nadir, downlight.Intensity(degrees, 0)/1000; math.Abs(got-want) > 1e-3 {
+				t.Errorf("intensity at %v degrees on side %v = %v of the nadir, want %v", degrees, side, got, want)
+			}
+		}
+		const steps = 200
+		var power float64
+		for i := 0; i < steps; i++ {
+			theta := (float64(i) + 0.5) * math.Pi / 2 / steps
+			power += emitted(theta, 0) * math.Sin(theta) * 2 * math.Pi * math.Pi / 2 / steps
+		}
+		if want := 2 * math.Pi; math.Abs(power-want)/want > 0.01 {
+			t.Errorf("power on side %v = %v, want %v", side, power, want)
+		}
+	}
+}
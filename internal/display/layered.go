@@ -0,0 +1,170 @@
+package display
+
+import (
+	"math"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+// maxLayerBounces is the number of times light may bounce between the coat and the base of a Layered
+// material before it is considered absorbed.
+const maxLayerBounces = 16
+
+// Layered represents a clear dielectric coat over any Base material, such as the clear coat of car paint or
+// the varnish on wood.
+//
+// Light is reflected off the coat or refracted through it to the base, where it is scattered by the base
+// material. Light scattered back up may then leave through the coat or bounce back down towards the base
+// again. The coat has a given Thickness and may tint the light crossing it with its Absorption.
+type Layered struct {
+	Base       Material
+	RefIndex   float64
+	Roughness  Texture
+	Thickness  float64
+	Absorption Absorbing
+}
+
+// NewLayered creates a new Layered material from a base material, coated with a clear coat of a given index
+// of refraction and roughness.
+func NewLayered(base Material, refIndex float64, roughness float64) Layered {
+	return Layered{Base: base, RefIndex: refIndex, Roughness: NewGray(roughness)}
+}
+
+// Emit returns the light emitted by the base material.
+func (l Layered) Emit(r *geometry.Ray, rec *HitRecord) Color {
+	return l.Base.Emit(r, rec)
+}
+
+// wrapped returns the base material under the coating.
+func (l Layered) wrapped() []Material {
+	return []Material{l.Base}
+}
+
+// Scatter follows light rays through the layers of the material until they leave it.
+func (l Layered) Scatter(r *geometry.Ray, rec *HitRecord) (bool, *Color, *geometry.Ray) {
+	basis, wo, back := localFrame(r, rec)
+	if back {
+		// The coat only covers the outside of the surface.
+		return l.Base.Scatter(r, rec)
+	}
+	roughness := intensity(l.Roughness, rec)
+	dist := newGGX(roughness, roughness)
+
+	wi, weight, ok := scatterDielectric(wo, dist, l.RefIndex, r.Rnd)
+	if !ok {
+		return false, &Color{}, &geometry.Ray{}
+	}
+	throughput := White.Scale(weight)
+	if wi.Z > 0 {
+		// Reflected off the coat.
+		scattered := scatterLocal(r, rec, basis, wi)
+		scattered.Spread = !dist.smooth()
+		return true, &throughput, scattered
+	}
+
+	// cross returns the light left after crossing the coat in the direction w.
+	cross := func(w geometry.Vec) Color {
+		if l.Thickness == 0 {
+			return White
+		}
+		return Spectral(l.Absorption.Transmittance(l.Thickness/math.Abs(w.Z)), r)
+	}
+	wavelengths := r.Wavelengths
+	for i := 0; i < maxLayerBounces; i++ {
+		throughput = throughput.Mul(cross(wi))
+
+		// Scatter off the base.
+		down := geometry.NewRay(rec.p, basis.World(wi).ToUnit(), r.Time, r.Rnd)
+		down.Wavelengths = wavelengths
+		wasScattered, attenuation, up := l.Base.Scatter(down, rec)
+		if !wasScattered {
+			return false, &Color{}, &geometry.Ray{}
+		}
+		throughput = throughput.Mul(spectralTint(down, *attenuation, up))
+		wavelengths = up.Wavelengths
+		wi = basis.Local(up.Direction.Vec)
+		if wi.Z <= 0 {
+			// Scattered through the base, such as into a dielectric.
+			return true, &throughput, up
+		}
+		throughput = throughput.Mul(cross(wi))
+
+		// Leave through the coat, or reflect off it back down.
+		wo = wi.Inv()
+		wo.Z = -wo.Z
+		wi, weight, ok = scatterDielectric(wo, dist, 1/l.RefIndex, r.Rnd)
+		if !ok {
+			return false, &Color{}, &geometry.Ray{}
+		}
+		throughput = throughput.Scale(weight)
+		wi.Z = -wi.Z
+		if wi.Z > 0 {
+			out := scatterLocal(r, rec, basis, wi)
+			out.Wavelengths = wavelengths
+			out.Spread = up.Spread
+			return true, &throughput, out
+		}
+	}
+	return false, &Color{}, &geometry.Ray{}
+}
+
+// Evaluate returns the light reflected from wi off the rough coat, and scattered by the base from light
+// refracted through the smooth coat on the way in and out.
+//
+// Light bouncing back and forth between the coat and the base is added as if the base scattered it evenly,
+// with the attenuation it has between the refracted directions. Rough coats refract light as smooth ones.
+func (l Layered) Evaluate(r *geometry.Ray, rec *HitRecord, wi geometry.Unit) (Color, float64) {
+	basis, wo, back := localFrame(r, rec)
+	if back {
+		return evaluate(l.Base, r, rec, wi)
+	}
+	local := basis.Local(wi.Vec)
+	if local.Z <= 0 {
+		return Black, 0
+	}
+	roughness := intensity(l.Roughness, rec)
+	value, pdf := Black, 0.0
+	if wm, v, density, ok := newGGX(roughness, roughness).reflection(wo, local); ok {
+		fresnel := fresnelDielectric(wo.Dot(wm), l.RefIndex)
+		value, pdf = White.Scale(fresnel*v), fresnel*density
+	}
+
+	// The directions of the ray going down to the base and of the light coming up from it, inside the coat.
+	up := geometry.NewVec(0, 0, 1)
+	down, _ := refract(wo, up, l.RefIndex)
+	in, _ := refract(local, up, l.RefIndex)
+	ray := geometry.NewRay(rec.p, basis.World(down).ToUnit(), r.Time, r.Rnd)
+	base, basePDF := evaluate(l.Base, ray, rec, basis.World(in.Inv()).ToUnit())
+	if basePDF <= 0 {
+		return value, pdf
+	}
+	// Crossing the coat scales the solid angle of the light by its index of refraction.
+	crossing := (1 - fresnelDielectric(wo.Z, l.RefIndex)) * (1 - fresnelDielectric(local.Z, l.RefIndex)) *
+		local.Z / (l.RefIndex * l.RefIndex * -in.Z)
+	internal := diffuseFresnel(1 / l.RefIndex)
+	bounces := func(albedo float64) float64 {
+		return 1 / (1 - clamp(albedo, 0, 1)*internal)
+	}
+	albedo := base.Scale(1 / basePDF)
+	if l.Thickness > 0 {
+		// Light scattered evenly by the base crosses the coat twice on average on each way.
+		albedo = albedo.Mul(l.Absorption.Transmittance(4 * l.Thickness))
+	}
+	through := base.Scale(crossing).Mul(NewColor(bounces(albedo.Red()), bounces(albedo.Green()), bounces(albedo.Blue())))
+	if l.Thickness > 0 {
+		through = through.Mul(l.Absorption.Transmittance(l.Thickness / -down.Z)).Mul(l.Absorption.Transmittance(l.Thickness / -in.Z))
+	}
+	return value.Add(through), pdf + basePDF*crossing/(1-internal)
+}
+
+// diffuseFresnel returns the share of light reflected by a smooth boundary, where eta is the ratio of the index
+// of refraction on the far side over the one on the near side, for light arriving evenly from the near side.
+func diffuseFresnel(eta float64) float64 {
+	const steps = 64
+	var reflected float64
+	for i := 0; i < steps; i++ {
+		cos := (float64(i) + 0.5) / steps
+		reflected += fresnelDielectric(cos, eta) * 2 * cos / steps
+	}
+	return reflected
+}
@@ -0,0 +1,153 @@
+package display
+
+import (
+	"math"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+// LightSource represents a light placed in the scene rather than a glowing surface, such as a bulb, a
+// spotlight or the sun. Rays never hit a LightSource by chance, so the integrator gathers its light at every
+// scattering event by sampling it directly, and checking that nothing stands in the way.
+type LightSource interface {
+	// Sample returns the light arriving at the point p from the light source.
+	Sample(p geometry.Vec, rnd geometry.Rnd) LightSample
+}
+
+// LightSample represents the light arriving at a point from a LightSource.
+type LightSample struct {
+	Direction geometry.Unit // from the lit point towards the light
+	Distance  float64       // from the lit point to the light, infinite for lights infinitely far away
+	Light     Color         // the light arriving at the point, divided by PDF unless it is 0
+	PDF       float64       // the density Direction was sampled with, or 0 for lights from a single direction
+}
+
+// Lumens returns the power in watts of a light with a given luminous flux in lumens, as if all of its light
+// was emitted at 555nm, where the eye is most sensitive.
+func Lumens(lm float64) float64 {
+	return lm / 683
+}
+
+// PointLight represents a light shining equally in all directions from a single point, such as a bare bulb.
+// Intensity is the power emitted per unit of solid angle, and the light falls off with the square of the
+// distance.
+//
+// A Profile shapes the light like a measured fixture hanging with its nadir along Down, or straight down
+// when Down is zero, keeping the same power.
+type PointLight struct {
+	Position  geometry.Vec
+	Intensity Color
+	Profile   *IESProfile
+	Down      geometry.Unit
+}
+
+// NewPointLight returns a new PointLight of a given color, emitting a total power in watts.
+func NewPointLight(position geometry.Vec, color Color, watts float64) PointLight {
+	return PointLight{Position: position, Intensity: color.Scale(watts / (4 * math.Pi))}
+}
+
+// Sample returns the light arriving at p from the point.
+func (l PointLight) Sample(p geometry.Vec, rnd geometry.Rnd) LightSample {
+	sample := towards(p, l.Position, l.Intensity)
+	if l.Profile != nil {
+		down := l.Down
+		if down.Zero() {
+			down = geometry.NewUnit(0, -1, 0)
+		}
+		sample.Light = sample.Light.Scale(l.Profile.relative(profileBasis(down), sample.Direction.Inv().Vec))
+	}
+	return sample
+}
+
+// SpotLight represents a light shining from a single point in a cone around a Direction, such as a stage
+// light or a torch.
+//
+// Angle is the angle in degrees between the axis and the edge of the cone, and the light fades out over
+// the last Falloff degrees before the edge. A Gobo texture may be projected by the light, such as a window
+// frame or leaves, with the UV coordinates spanning the cone. A Profile shapes the light within the cone
+// like a measured fixture with its nadir along the Direction.
+type SpotLight struct {
+	Position  geometry.Vec
+	Direction geometry.Unit
+	Intensity Color
+	Angle     float64
+	Falloff   float64
+	Gobo      Texture
+	Profile   *IESProfile
+}
+
+// NewSpotLight returns a new SpotLight of a given color, emitting a total power in watts within its cone.
+func NewSpotLight(position geometry.Vec, direction geometry.Vec, color Color, watts float64, angle float64, falloff float64) SpotLight {
+	s := SpotLight{Position: position, Direction: direction.ToUnit(), Angle: angle, Falloff: falloff}
+	// The power spread over the cone, counting the falloff as half lit.
+	solidAngle := 2 * math.Pi * (1 - (s.cosInner()+s.cosOuter())/2)
+	s.Intensity = color.Scale(watts / solidAngle)
+	return s
+}
+
+// Sample returns the light arriving at p from the spotlight, which is dark outside its cone.
+func (s SpotLight) Sample(p geometry.Vec, rnd geometry.Rnd) LightSample {
+	sample := towards(p, s.Position, s.Intensity)
+	out := sample.Direction.Inv()
+	cos := out.Dot(s.Direction)
+	cosOuter, cosInner := s.cosOuter(), s.cosInner()
+	if cos <= cosOuter {
+		sample.Light = Black
+		return sample
+	}
+	if cos < cosInner {
+		t := (cos - cosOuter) / (cosInner - cosOuter)
+		sample.Light = sample.Light.Scale(t * t * (3 - 2*t))
+	}
+	if s.Gobo != nil {
+		local := geometry.NewBasis(s.Direction).Local(out.Vec)
+		spread := 2 * math.Tan(radians(s.Angle)) * local.Z
+		sample.Light = sample.Light.Mul(s.Gobo.At(0.5+local.X/spread, 0.5+local.Y/spread, p))
+	}
+	if s.Profile != nil {
+		sample.Light = sample.Light.Scale(s.Profile.relative(profileBasis(s.Direction), out.Vec))
+	}
+	return sample
+}
+
+// cosOuter returns the cosine of the angle at the edge of the cone.
+func (s SpotLight) cosOuter() float64 {
+	return math.Cos(radians(clamp(s.Angle, 0, 90)))
+}
+
+// cosInner returns the cosine of the angle where the light starts fading out.
+func (s SpotLight) cosInner() float64 {
+	return math.Cos(radians(clamp(s.Angle-s.Falloff, 0, 90)))
+}
+
+// DirectionalLight represents a light so far away that its rays arrive in parallel from a single Direction,
+// such as the sun. Irradiance is the power received per unit of area facing the light.
+type DirectionalLight struct {
+	Direction  geometry.Unit // the direction the light travels in
+	Irradiance Color
+}
+
+// NewDirectionalLight returns a new DirectionalLight travelling in a direction, of a given color and
+// irradiance in watts per unit of area.
+func NewDirectionalLight(direction geometry.Vec, color Color, irradiance float64) DirectionalLight {
+	return DirectionalLight{Direction: direction.ToUnit(), Irradiance: color.Scale(irradiance)}
+}
+
+// Sample returns the light arriving at p from infinitely far away.
+func (d DirectionalLight) Sample(p geometry.Vec, rnd geometry.Rnd) LightSample {
+	return LightSample{Direction: d.Direction.Inv(), Distance: math.Inf(1), Light: d.Irradiance}
+}
+
+// profileBasis returns the basis orienting an IESProfile with its nadir along down, and its horizontal
+// angle of 0 degrees as close to +X as possible.
+func profileBasis(down geometry.Unit) geometry.Basis {
+	return geometry.NewBasisFromTangent(down, geometry.NewVec(1, 0, 0))
+}
+
+// towards returns the light of a given intensity arriving at p from a point light at the position,
+// falling off with the square of the distance.
+func towards(p geometry.Vec, position geometry.Vec, intensity Color) LightSample {
+	offset := position.Sub(p)
+	d2 := offset.LenSquared()
+	return LightSample{Direction: offset.ToUnit(), Distance: math.Sqrt(d2), Light: intensity.Scale(1 / d2)}
+}
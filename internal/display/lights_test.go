@@ -0,0 +1,143 @@
+package display
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+func TestLightPower(t *testing.T) {
+	tests := []struct {
+		name  string
+		light LightSource
+		watts float64
+	}{
+		{name: "point", light: NewPointLight(geometry.Vec{}, White, 100), watts: 100},
+		{name: "narrow spot", light: NewSpotLight(geometry.Vec{}, geometry.NewVec(0, -1, 0), White, 100, 20, 0), watts: 100},
+		{name: "soft spot", light: NewSpotLight(geometry.Vec{}, geometry.NewVec(1, 1, 0), White, 100, 45, 30), watts: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The power is the light arriving on a unit sphere around the light, integrated over the sphere.
+			power := sphereIntegral(func(dir geometry.Unit) float64 {
+				return tt.light.Sample(dir.Vec, nil).Light.Red()
+			})
+			// The smoothstep falloff differs a little from a linear fade of the solid angle.
+			if math.Abs(power-tt.watts)/tt.watts > 0.02 {
+				t.Errorf("power = %v, want %v", power, tt.watts)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	rough := NewPrincipled(NewSolid(NewColor(0.8, 0.4, 0.2)), 0.5, 0.4)
+	rough.Clearcoat = NewGray(1)
+	rough.ClearcoatRoughness = NewGray(0.3)
+	rough.Sheen = NewGray(0.5)
+	glassy := NewPrincipled(NewSolid(NewColor(0.9, 0.9, 0.9)), 0, 0.3)
+	glassy.Transmission = NewGray(0.8)
+	varnished := NewLayered(NewLambertian(NewSolid(NewColor(0.7, 0.3, 0.2))), 1.5, 0.1)
+	varnished.Thickness = 0.2
+	varnished.Absorption = NewAbsorbing(NewColor(0.8, 0.4, 0.4))
+
+	tests := []struct {
+		name     string
+		material Material
+	}{
+		{name: "lambertian", material: NewLambertian(NewSolid(NewColor(0.5, 0.5, 0.5)))},
+		{name: "oren-nayar", material: NewOrenNayar(NewSolid(NewColor(0.5, 0.5, 0.5)), 30)},
+		{name: "conductor", material: NewPresetConductor("gold", 0.4)},
+		{name: "principled", material: rough},
+		{name: "mix", material: NewMix(NewLambertian(NewSolid(White)), NewPresetConductor("copper", 0.3), NewGray(0.3))},
+		{name: "anisotropic", material: NewAnisotropic(NewSolid(NewColor(0.9, 0.9, 0.9)), 0.6)},
+		{name: "rough dielectric", material: NewRoughDielectric(1.5, 0.3)},
+		{name: "principled glass", material: glassy},
+		{name: "retroreflective", material: NewRetroreflective(NewSolid(NewColor(0.8, 0.8, 0.8)), 0.5, 20)},
+		{name: "layered", material: varnished},
+		{name: "thin film", material: NewThinFilm(NewLambertian(NewSolid(NewColor(0.5, 0.5, 0.5))), 400, 1.4)},
+		{name: "thin film on metal", material: NewThinFilm(NewPresetConductor("silver", 0.3), 300, 1.4)},
+	}
+	rnd := rand.New(rand.NewSource(1))
+	r := geometry.NewRay(geometry.Vec{}, geometry.NewVec(1, 0, -2).ToUnit(), 0, rnd)
+	rec := &HitRecord{normal: geometry.NewUnit(0, 0, 1)}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const samples = 200000
+			// The light scattered on average by Scatter matches the integral of Evaluate over the sphere,
+			// and the density of Evaluate integrates to the share of rays that Scatter does not drop.
+			// Rays mirrored by a smooth coat are left out, as direct light cannot reach them.
+			mirror := r.Direction.Reflect(rec.normal)
+			var scattered, kept float64
+			for i := 0; i < samples; i++ {
+				if ok, attenuation, out := tt.material.Scatter(r, rec); ok && out.Direction.Dot(mirror) < 1-1e-9 {
+					scattered += attenuation.Red() / samples
+					kept += 1.0 / samples
+				}
+			}
+			// Evaluate is integrated over a grid, as the narrow lobes of smooth materials are easily missed at random.
+			evaluated := sphereIntegral(func(wi geometry.Unit) float64 {
+				value, _ := tt.material.(Evaluator).Evaluate(r, rec, wi)
+				return value.Red()
+			})
+			density := sphereIntegral(func(wi geometry.Unit) float64 {
+				_, pdf := tt.material.(Evaluator).Evaluate(r, rec, wi)
+				return pdf
+			})
+			if math.Abs(scattered-evaluated) > 0.02 {
+				t.Errorf("Evaluate() integrates to %v, want %v as scattered", evaluated, scattered)
+			}
+			if math.Abs(density-kept) > 0.02 {
+				t.Errorf("Evaluate() density integrates to %v, want %v", density, kept)
+			}
+		})
+	}
+}
+
+// sphereIntegral returns the integral of f over the unit sphere, summed over a grid of directions of equal area.
+func sphereIntegral(f func(dir geometry.Unit) float64) float64 {
+	const steps = 400
+	var sum float64
+	for i := 0; i < steps; i++ {
+		cos := -1 + (float64(i)+0.5)*2/steps
+		sin := math.Sqrt(1 - cos*cos)
+		for j := 0; j < steps; j++ {
+			phi := (float64(j) + 0.5) * 2 * math.Pi / steps
+			sum += f(geometry.NewUnit(sin*math.Cos(phi), sin*math.Sin(phi), cos)) * 4 * math.Pi / (steps * steps)
+		}
+	}
+	return sum
+}
+
+// distantLight is a LightSource lighting the scene from all around, such as an Environment or a Sky.
+type distantLight interface {
+	LightSource
+	Radiance(dir geometry.Unit) Color
+	PDF(dir geometry.Unit) float64
+}
+
+// checkSampling checks that the directions sampled from a light match its PDF and Radiance, and that the
+// light sampled adds up to the irradiance within a relative tolerance.
+func checkSampling(t *testing.T, light distantLight, irradiance float64, samples int, tolerance float64) {
+	t.Helper()
+	rnd := rand.New(rand.NewSource(1))
+	var estimate float64
+	for i := 0; i < samples; i++ {
+		s := light.Sample(geometry.Vec{}, rnd)
+		if s.PDF <= 0 {
+			t.Fatalf("Sample() has a PDF of %v", s.PDF)
+		}
+		if pdf := light.PDF(s.Direction); math.Abs(pdf-s.PDF) > 1e-6*pdf {
+			t.Fatalf("PDF() = %v, want %v as sampled", pdf, s.PDF)
+		}
+		if want := light.Radiance(s.Direction).Red(); math.Abs(s.Light.Red()*s.PDF-want) > 1e-6*want {
+			t.Fatalf("Sample() light = %v, want %v", s.Light.Red()*s.PDF, want)
+		}
+		estimate += s.Light.Red() / float64(samples)
+	}
+	if math.Abs(estimate-irradiance)/irradiance > tolerance {
+		t.Errorf("sampled light = %v, want %v", estimate, irradiance)
+	}
+}
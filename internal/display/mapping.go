@@ -0,0 +1,129 @@
+package display
+
+import (
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+// NormalMapped represents a Base material whose shading normals follow a tangent-space normal map, adding
+// surface detail without extra geometry.
+//
+// The red, green and blue channels of the Map hold the X, Y and Z of the normal in a frame where X follows
+// the direction in which u increases, Y the direction in which v increases and Z the normal of the surface,
+// as in the usual bluish normal map images. Strength scales the tilt of the normals, where 1 follows the map.
+type NormalMapped struct {
+	Base     Material
+	Map      Texture
+	Strength float64
+}
+
+// NewNormalMapped creates a new NormalMapped from a base material and a normal map.
+func NewNormalMapped(base Material, normals Texture) NormalMapped {
+	return NormalMapped{Base: base, Map: normals, Strength: 1}
+}
+
+// Scatter scatters light rays off the base material, using the normal from the map.
+func (n NormalMapped) Scatter(r *geometry.Ray, rec *HitRecord) (bool, *Color, *geometry.Ray) {
+	return n.Base.Scatter(r, n.shade(rec))
+}
+
+// Evaluate returns the light scattered from wi by the base material, using the normal from the map.
+func (n NormalMapped) Evaluate(r *geometry.Ray, rec *HitRecord, wi geometry.Unit) (Color, float64) {
+	return evaluate(n.Base, r, n.shade(rec), wi)
+}
+
+// shade returns a copy of the HitRecord with the normal from the map.
+func (n NormalMapped) shade(rec *HitRecord) *HitRecord {
+	c := n.Map.At(rec.u, rec.v, rec.p)
+	local := geometry.NewVec((2*c.Red()-1)*n.Strength, (2*c.Green()-1)*n.Strength, 2*c.Blue()-1)
+	if local.Z < 0.01 {
+		local.Z = 0.01
+	}
+	dpdu, _ := rec.tangents()
+	basis := geometry.NewBasisFromTangent(rec.normal, dpdu)
+	return shade(rec, basis.World(local))
+}
+
+// Emit returns the light emitted by the base material.
+func (n NormalMapped) Emit(r *geometry.Ray, rec *HitRecord) Color {
+	return n.Base.Emit(r, rec)
+}
+
+// wrapped returns the base material.
+func (n NormalMapped) wrapped() []Material {
+	return []Material{n.Base}
+}
+
+// BumpMapped represents a Base material whose shading normals follow the slopes of a height map, such as an
+// image or Perlin noise, adding surface detail without extra geometry.
+//
+// The Height texture is read as a grayscale value and scaled by Scale, the height in world units of a white
+// texel, along the normal of the surface.
+type BumpMapped struct {
+	Base   Material
+	Height Texture
+	Scale  float64
+}
+
+// NewBumpMapped creates a new BumpMapped from a base material, a height map and the height of a white texel.
+func NewBumpMapped(base Material, height Texture, scale float64) BumpMapped {
+	return BumpMapped{Base: base, Height: height, Scale: scale}
+}
+
+// bumpDelta is the step in surface coordinates used to measure the slopes of a height map.
+const bumpDelta = 0.0005
+
+// Scatter scatters light rays off the base material, using the normal of the bumpy surface.
+func (b BumpMapped) Scatter(r *geometry.Ray, rec *HitRecord) (bool, *Color, *geometry.Ray) {
+	return b.Base.Scatter(r, b.shade(rec))
+}
+
+// Evaluate returns the light scattered from wi by the base material, using the normal of the bumpy surface.
+func (b BumpMapped) Evaluate(r *geometry.Ray, rec *HitRecord, wi geometry.Unit) (Color, float64) {
+	return evaluate(b.Base, r, b.shade(rec), wi)
+}
+
+// shade returns a copy of the HitRecord with the normal of the bumpy surface.
+func (b BumpMapped) shade(rec *HitRecord) *HitRecord {
+	dpdu, dpdv := rec.tangents()
+	// Moving along dpdv on the back of a surface moves back along v.
+	vStep := 1.0
+	if rec.mirrored {
+		vStep = -1
+	}
+	height := func(du float64, dv float64) float64 {
+		p := rec.p.Add(dpdu.Scale(du)).Add(dpdv.Scale(dv))
+		c := b.Height.At(rec.u+du, rec.v+vStep*dv, p)
+		return (c.Red() + c.Green() + c.Blue()) / 3 * b.Scale
+	}
+	h := height(0, 0)
+	dhdu := (height(bumpDelta, 0) - h) / bumpDelta
+	dhdv := (height(0, bumpDelta) - h) / bumpDelta
+
+	// Displace the tangents along the normal and take the normal of the displaced surface.
+	n := rec.normal.Vec
+	normal := dpdu.Add(n.Scale(dhdu)).Cross(dpdv.Add(n.Scale(dhdv)))
+	if normal.Dot(n) < 0 {
+		normal = normal.Inv()
+	}
+	return shade(rec, normal)
+}
+
+// Emit returns the light emitted by the base material.
+func (b BumpMapped) Emit(r *geometry.Ray, rec *HitRecord) Color {
+	return b.Base.Emit(r, rec)
+}
+
+// wrapped returns the base material.
+func (b BumpMapped) wrapped() []Material {
+	return []Material{b.Base}
+}
+
+// shade returns a copy of a HitRecord with its normal replaced by a shading normal, falling back on
+// the original normal when the shading normal is degenerate.
+func shade(rec *HitRecord, normal geometry.Vec) *HitRecord {
+	shaded := *rec
+	if normal.LenSquared() > 1e-12 {
+		shaded.normal = normal.ToUnit()
+	}
+	return &shaded
+}
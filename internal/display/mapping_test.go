@@ -0,0 +1,57 @@
+package display
+
+import (
+	"math"
+	"testing"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+func TestTangentFrame(t *testing.T) {
+	up := geometry.NewUnit(0, 1, 0)
+	down := geometry.NewUnit(0, -1, 0)
+	tests := []struct {
+		name    string
+		surface HitBoxer
+		origin  geometry.Vec
+		dir     geometry.Unit
+	}{
+		{name: "rectangle", surface: NewRectangle(geometry.NewVec(-1, 0, -1), geometry.NewVec(1, 0, 1), nil), origin: geometry.NewVec(0.3, 2, 0.2), dir: down},
+		{name: "flipped rectangle", surface: NewFlip(NewRectangle(geometry.NewVec(-1, 0, -1), geometry.NewVec(1, 0, 1), nil)), origin: geometry.NewVec(0.3, -2, 0.2), dir: up},
+		{name: "disk", surface: NewDisk(geometry.Vec{}, 1, nil), origin: geometry.NewVec(0.3, 2, 0.2), dir: down},
+		{name: "top cap", surface: NewCylinder(geometry.Vec{}, 1, 1, nil), origin: geometry.NewVec(0.3, 3, 0.2), dir: down},
+		{name: "bottom cap", surface: NewCylinder(geometry.Vec{}, 1, 1, nil), origin: geometry.NewVec(0.3, -2, 0.2), dir: up},
+		{name: "carved out", surface: NewDifference(NewBlock(geometry.NewVec(-1, -1, -1), geometry.NewVec(1, 1, 1), nil), NewSphere(geometry.NewVec(0, 1, 0), 0.5, nil)), origin: geometry.NewVec(0.1, 3, 0.05), dir: down},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hit, rec := tt.surface.Hit(geometry.NewRay(tt.origin, tt.dir, 0, nil), 0.001, math.Inf(1))
+			if !hit {
+				t.Fatal("Hit() missed")
+			}
+			if rec.normal.Dot(tt.dir) >= 0 {
+				t.Fatalf("normal %v does not face the ray", rec.normal)
+			}
+			if handedness := rec.dpdu.Cross(rec.dpdv).Dot(rec.normal.Vec); handedness <= 0 {
+				t.Errorf("dpdu x dpdv . normal = %v, want a right-handed frame", handedness)
+			}
+			// Moving the hit point along each tangent moves its coordinates along the matching direction, except
+			// for dpdv on the back of a surface.
+			const step = 1e-4
+			vStep := step
+			if rec.mirrored {
+				vStep = -step
+			}
+			for _, tangent := range []struct {
+				name   string
+				d      geometry.Vec
+				du, dv float64
+			}{{"dpdu", rec.dpdu, step, 0}, {"dpdv", rec.dpdv, 0, vStep}} {
+				_, moved := tt.surface.Hit(geometry.NewRay(tt.origin.Add(tangent.d.Scale(step)), tt.dir, 0, nil), 0.001, math.Inf(1))
+				if du, dv := moved.u-rec.u, moved.v-rec.v; math.Abs(du-tangent.du) > step/100 || math.Abs(dv-tangent.dv) > step/100 {
+					t.Errorf("moving along %s changes u, v by %v, %v, want %v, %v", tangent.name, du, dv, tangent.du, tangent.dv)
+				}
+			}
+		})
+	}
+}
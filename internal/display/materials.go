@@ -9,14 +9,41 @@ import (
 // Material represents a material that scatters light.
 type Material interface {
 	Scatter(r *geometry.Ray, rec *HitRecord) (wasScattered bool, attenuation *Color, scattered *geometry.Ray)
-	Emit(rec *HitRecord) Color
+	Emit(r *geometry.Ray, rec *HitRecord) Color
+}
+
+// Evaluator is implemented by a Material that can tell how much light it scatters between two given
+// directions, so that light arriving from a LightSource can be gathered directly.
+//
+// Scatter marks the rays it scatters in directions covered by Evaluate as Spread. Other rays, such as those
+// reflected off smooth lobes, are picked from a few possible directions that lights cannot be sampled in.
+type Evaluator interface {
+	// Evaluate returns the light scattered from the direction wi towards the viewer of the ray r, times the
+	// cosine of wi to the normal, and the density with which Scatter samples wi.
+	Evaluate(r *geometry.Ray, rec *HitRecord, wi geometry.Unit) (Color, float64)
+}
+
+// evaluate returns the result of Evaluate for a material that implements it, or nothing for materials that
+// only scatter light in a few directions, which direct light cannot reach.
+func evaluate(m Material, r *geometry.Ray, rec *HitRecord, wi geometry.Unit) (Color, float64) {
+	if e, ok := m.(Evaluator); ok {
+		return e.Evaluate(r, rec, wi)
+	}
+	return Black, 0
+}
+
+// spread marks a ray as scattered in a direction picked from a continuous spread of directions, as covered by
+// Evaluate, and returns it.
+func spread(ray *geometry.Ray) *geometry.Ray {
+	ray.Spread = true
+	return ray
 }
 
 // nonEmitter represents an emitter that does not emit light.
 type nonEmitter struct{}
 
 // Emit returns Black.
-func (n nonEmitter) Emit(rec *HitRecord) Color {
+func (n nonEmitter) Emit(r *geometry.Ray, rec *HitRecord) Color {
 	return Black
 }
 
@@ -31,11 +58,22 @@ func NewLambertian(albedo Texture) Lambertian {
 	return Lambertian{Albedo: albedo}
 }
 
-// Scatter scatters light rays in a Lambertian pattern.
+// Scatter scatters light rays in a Lambertian pattern, with a density proportional to the cosine
+// of the angle to the normal.
 func (l Lambertian) Scatter(r *geometry.Ray, rec *HitRecord) (bool, *Color, *geometry.Ray) {
-	out := rec.normal.Add(geometry.RandVecInSphere(r.Rnd)).ToUnit()
+	basis, _, _ := localFrame(r, rec)
 	attenuation := l.Albedo.At(rec.u, rec.v, rec.p)
-	return true, &attenuation, geometry.NewRay(rec.p, out.ToUnit(), r.Time, r.Rnd)
+	return true, &attenuation, spread(scatterLocal(r, rec, basis, geometry.RandCosineHemisphere(r.Rnd)))
+}
+
+// Evaluate returns the light scattered from wi, spread evenly over the hemisphere.
+func (l Lambertian) Evaluate(r *geometry.Ray, rec *HitRecord, wi geometry.Unit) (Color, float64) {
+	basis, _, _ := localFrame(r, rec)
+	cos := basis.Local(wi.Vec).Z
+	if cos <= 0 {
+		return Black, 0
+	}
+	return l.Albedo.At(rec.u, rec.v, rec.p).Scale(cos / math.Pi), cos / math.Pi
 }
 
 // Metal represents a reflective material.
@@ -59,7 +97,10 @@ func (m Metal) Scatter(r *geometry.Ray, rec *HitRecord) (bool, *Color, *geometry
 
 // Dielectric represents a clear material.
 type Dielectric struct {
-	RefIndex float64
+	RefIndex   float64
+	Inside     Medium          // the medium filling the material, which may absorb light travelling through it
+	Dispersion RefractiveIndex // how the index of refraction varies with wavelength when rendering spectrally
+	Priority   int             // the priority of the material where it overlaps others, see Nested
 	nonEmitter
 }
 
@@ -68,19 +109,49 @@ func NewDielectric(refIndex float64) Dielectric {
 	return Dielectric{RefIndex: refIndex}
 }
 
+// NewAbsorbingDielectric creates a new material with a given index of refraction, filled with
+// a medium that absorbs light travelling through it, such as coloured glass or a liquid.
+func NewAbsorbingDielectric(refIndex float64, absorption Absorbing) Dielectric {
+	return Dielectric{RefIndex: refIndex, Inside: absorption}
+}
+
+// NewDispersiveDielectric creates a new material whose index of refraction varies with the wavelength
+// of light, splitting white light into its colors when rendering spectrally. When rendering in RGB,
+// the index of refraction at the yellow sodium line is used for every channel.
+func NewDispersiveDielectric(dispersion RefractiveIndex) Dielectric {
+	return Dielectric{RefIndex: dispersion.At(587.6), Dispersion: dispersion}
+}
+
+// Interior returns the medium filling the material.
+func (d Dielectric) Interior() Medium {
+	return d.Inside
+}
+
+// Nesting returns the priority and the index of refraction of the material.
+func (d Dielectric) Nesting() (int, float64) {
+	return d.Priority, d.RefIndex
+}
+
 // Scatter reflects or refracts light rays based on the index of refraction.
 func (d Dielectric) Scatter(r *geometry.Ray, rec *HitRecord) (bool, *Color, *geometry.Ray) {
 	in := r.Direction
 	n := rec.normal
 
+	refIndex := d.RefIndex
+	dispersed := d.Dispersion != nil && !r.Wavelengths.Zero()
+	if dispersed {
+		refIndex = d.Dispersion.At(r.Wavelengths.X)
+	}
+	refIndex /= rec.outer()
+
 	outNormal := n
-	ratio := 1 / d.RefIndex
+	ratio := 1 / refIndex
 	cosTheta := -in.Dot(n) / in.Len()
 
 	if in.Dot(n) > 0 {
 		outNormal = n.Inv()
-		ratio = d.RefIndex
-		cosTheta = d.RefIndex * in.Dot(n) / in.Len()
+		ratio = refIndex
+		cosTheta = refIndex * in.Dot(n) / in.Len()
 	}
 
 	refracted, out := geometry.Refract(in, outNormal, ratio)
@@ -89,7 +160,12 @@ func (d Dielectric) Scatter(r *geometry.Ray, rec *HitRecord) (bool, *Color, *geo
 		a := in.Reflect(n)
 		out = &a
 	}
-	return true, &White, geometry.NewRay(rec.p, out.ToUnit(), r.Time, r.Rnd)
+	scattered := geometry.NewRay(rec.p, out.ToUnit(), r.Time, r.Rnd)
+	if dispersed {
+		// Each wavelength bends by a different amount, so only the hero wavelength can follow the ray.
+		scattered.Wavelengths = geometry.NewVec(r.Wavelengths.X, 0, 0)
+	}
+	return true, &White, scattered
 }
 
 // schlick calculates Schlick's approximation for the contribution of the Fresnel factor in the reflection of light from a surface.
@@ -99,16 +175,23 @@ func schlick(cos float64, refIndex float64) float64 {
 	return r + (1-r)*math.Pow(1-cos, 5)
 }
 
-// Light represents a material that emits light.
+// Light represents a material that emits light from both sides of its surface.
 type Light struct {
-	Solid Solid
+	// Deprecated: Solid is only used when Emission is nil; set Emission instead.
+	Solid    Solid
+	Emission Texture
 }
 
-// NewLight returns a new Light.
+// NewLight returns a new Light of a single color.
 func NewLight(c Color) *Light {
 	return &Light{Solid: NewSolid(c)}
 }
 
+// NewTexturedLight returns a new Light whose color varies over its surface, such as a screen.
+func NewTexturedLight(t Texture) *Light {
+	return &Light{Emission: t}
+}
+
 // Scatter does not reflect light rays.
 // A light source emits rays but does not reflect rays.
 func (l Light) Scatter(r *geometry.Ray, rec *HitRecord) (bool, *Color, *geometry.Ray) {
@@ -116,8 +199,11 @@ func (l Light) Scatter(r *geometry.Ray, rec *HitRecord) (bool, *Color, *geometry
 }
 
 // Emit returns the Color of the ray at the coordinates of the given HitRecord.
-func (l Light) Emit(rec *HitRecord) Color {
-	return l.Solid.At(rec.u, rec.v, rec.p)
+func (l Light) Emit(r *geometry.Ray, rec *HitRecord) Color {
+	if l.Emission == nil {
+		return l.Solid.At(rec.u, rec.v, rec.p)
+	}
+	return l.Emission.At(rec.u, rec.v, rec.p)
 }
 
 // Isotropic represents a material of constant density.
@@ -135,6 +221,11 @@ func NewIsotropic(albedo Solid, rnd geometry.Rnd) *Isotropic {
 // Scatter reflects light in a random direction.
 func (i *Isotropic) Scatter(r *geometry.Ray, rec *HitRecord) (bool, *Color, *geometry.Ray) {
 	color := i.albedo.At(rec.u, rec.v, rec.p)
-	r.Direction = geometry.RandUnit(r.Rnd)
-	return true, &color, r
+	return true, &color, spread(geometry.NewRay(rec.p, UniformPhase{}.Sample(r.Direction, r.Rnd), r.Time, r.Rnd))
+}
+
+// Evaluate returns the light scattered from wi, which is the same from all directions.
+func (i *Isotropic) Evaluate(r *geometry.Ray, rec *HitRecord, wi geometry.Unit) (Color, float64) {
+	pdf := UniformPhase{}.PDF(r.Direction, wi)
+	return i.albedo.At(rec.u, rec.v, rec.p).Scale(pdf), pdf
 }
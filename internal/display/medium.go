@@ -0,0 +1,282 @@
+package display
+
+import (
+	"math"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+// Medium represents the matter filling the inside of a solid, which light travels through between surfaces.
+type Medium interface {
+	// Transmittance returns the fraction of light of each channel that makes it through a distance d of the medium.
+	Transmittance(d float64) Color
+}
+
+// Bounded is implemented by the materials of surfaces enclosing a Medium, such as coloured glass.
+type Bounded interface {
+	// Interior returns the medium on the inside of the surface, or nil when the inside is empty.
+	Interior() Medium
+}
+
+// Absorbing represents a clear Medium that absorbs light exponentially with distance, following the Beer-Lambert law.
+type Absorbing struct {
+	Coefficient Color // the fraction of light of each channel absorbed per unit of distance
+}
+
+// NewAbsorbing returns a new Absorbing medium with the given absorption coefficients.
+func NewAbsorbing(coefficient Color) Absorbing {
+	return Absorbing{Coefficient: coefficient}
+}
+
+// NewAbsorbingColor returns a new Absorbing medium that tints white light to the given color
+// after it has travelled the given distance through the medium.
+func NewAbsorbingColor(tint Color, distance float64) Absorbing {
+	coefficient := func(c float64) float64 {
+		return -math.Log(math.Max(c, 1e-6)) / distance
+	}
+	return NewAbsorbing(NewColor(coefficient(tint.Red()), coefficient(tint.Green()), coefficient(tint.Blue())))
+}
+
+// Transmittance returns the fraction of light of each channel left after travelling a distance d.
+func (a Absorbing) Transmittance(d float64) Color {
+	return NewColor(
+		math.Exp(-a.Coefficient.Red()*d),
+		math.Exp(-a.Coefficient.Green()*d),
+		math.Exp(-a.Coefficient.Blue()*d),
+	)
+}
+
+// Nested is implemented by Bounded materials that may overlap other Bounded materials, such as a liquid filling
+// a glass, or fog filling a room with glass windows.
+//
+// Where materials overlap, the medium of the one with the highest priority fills the overlap, and the surfaces
+// of the others are ignored there. The liquid in a glass can then be modelled slightly larger than the inside
+// of the glass, with a lower priority than the glass, and light crossing from the glass into the liquid is
+// refracted following the ratio of their indices of refraction.
+type Nested interface {
+	Bounded
+	// Nesting returns the priority of the material and its index of refraction.
+	Nesting() (int, float64)
+}
+
+// wrapper is implemented by materials that wrap other materials, such as Emissive or Mix. The surface of a
+// wrapper bounds the medium of the first material it wraps that bounds one, and no medium otherwise.
+type wrapper interface {
+	wrapped() []Material
+}
+
+// bounding returns the material bounding the medium inside a surface of material m: m itself when it is
+// Bounded, or else the first Bounded material found within the materials it wraps. Returns nil when the
+// surface bounds no medium.
+func bounding(m Material) Bounded {
+	if b, ok := m.(Bounded); ok {
+		return b
+	}
+	if w, ok := m.(wrapper); ok {
+		for _, inner := range w.wrapped() {
+			if b := bounding(inner); b != nil {
+				return b
+			}
+		}
+	}
+	return nil
+}
+
+// nesting returns the priority and the index of refraction of a material, which are 0 and 1 for materials
+// that are not Nested.
+func nesting(m Material) (int, float64) {
+	if n, ok := bounding(m).(Nested); ok {
+		return n.Nesting()
+	}
+	return 0, 1
+}
+
+// mediumEntry represents a medium entered by a ray through the surface of a Bounded material.
+type mediumEntry struct {
+	medium   Medium
+	priority int
+	refIndex float64
+}
+
+// MediumStack holds the media that a ray travels through, in the order it entered them through the surfaces
+// of Bounded materials, on top of the medium filling the whole scene. Crossing a surface outwards returns to
+// the media entered before it, so that volumes may be nested or overlap.
+//
+// A MediumStack is never changed in place, so that the rays scattered from a path may share it.
+type MediumStack struct {
+	base    Medium
+	entries []mediumEntry
+}
+
+// NewMediumStack returns a new MediumStack for rays starting out in the medium filling the whole scene,
+// which may be nil for empty space.
+func NewMediumStack(base Medium) MediumStack {
+	return MediumStack{base: base}
+}
+
+// Current returns the medium that rays travel through: the one of highest priority, or the last one entered
+// among those of equal priority.
+func (s MediumStack) Current() Medium {
+	top := s.top(-1)
+	if top < 0 {
+		return s.base
+	}
+	return s.entries[top].medium
+}
+
+// Resolve works out the surface described by rec, hit by a ray r travelling through the media of the stack.
+//
+// Returns false when the surface lies within a medium of higher priority than its own, in which case the ray
+// should go through it unchanged, see Cross. Otherwise, the index of refraction of the medium on the far side
+// of the surface from its material is recorded on rec, for dielectrics to refract against.
+func (s MediumStack) Resolve(r *geometry.Ray, rec *HitRecord) bool {
+	entry, ok := boundedEntry(rec.Material)
+	if !ok {
+		return true
+	}
+	own := -1
+	if r.Direction.Dot(rec.normal) > 0 {
+		own = s.find(entry)
+	}
+	top := s.top(own)
+	if top >= 0 && s.entries[top].priority > entry.priority {
+		return false
+	}
+	rec.outerIOR = 1
+	if top >= 0 {
+		rec.outerIOR = s.entries[top].refIndex
+	}
+	return true
+}
+
+// Cross returns the ray r carrying on unchanged through the surface described by rec, along with the media
+// it then travels through.
+func (s MediumStack) Cross(r *geometry.Ray, rec *HitRecord) (*geometry.Ray, MediumStack) {
+	crossed := geometry.NewRay(rec.p, r.Direction, r.Time, r.Rnd)
+	crossed.Wavelengths = r.Wavelengths
+	return crossed, s.Next(r, rec, crossed)
+}
+
+// Next returns the media that the scattered ray out travels through, after the ray in hit the surface
+// described by rec.
+//
+// Rays crossing into a Bounded surface enter its interior, and rays crossing out of it return to the media
+// they were in before. Rays reflected off a surface, or scattered by a material that bounds no medium, stay
+// in the current media.
+func (s MediumStack) Next(in *geometry.Ray, rec *HitRecord, out *geometry.Ray) MediumStack {
+	entry, ok := boundedEntry(rec.Material)
+	if !ok {
+		return s
+	}
+	wasInside := in.Direction.Dot(rec.normal) > 0
+	goesInside := out.Direction.Dot(rec.normal) < 0
+	switch {
+	case !wasInside && goesInside:
+		entries := make([]mediumEntry, len(s.entries), len(s.entries)+1)
+		copy(entries, s.entries)
+		return MediumStack{base: s.base, entries: append(entries, entry)}
+	case wasInside && !goesInside:
+		i := s.find(entry)
+		if i < 0 {
+			// The ray left a surface it never entered, such as one around the camera.
+			return s
+		}
+		entries := make([]mediumEntry, 0, len(s.entries)-1)
+		entries = append(entries, s.entries[:i]...)
+		return MediumStack{base: s.base, entries: append(entries, s.entries[i+1:]...)}
+	default:
+		return s
+	}
+}
+
+// maxVisibilityCrossings is the most surfaces that Visibility lets light through before giving up.
+const maxVisibilityCrossings = 64
+
+// Visibility returns the fraction of light of each channel making it along the ray r over the distance d, such
+// as from a LightSource, through the media of the stack, given at the wavelengths of r.
+//
+// Light is blocked by every surface on the way, but for the boundaries of media and surfaces hidden within
+// media of higher priority. Light from infinitely far away only travels through the media up to the edge of
+// the scene.
+func (s MediumStack) Visibility(hb HitBoxer, r *geometry.Ray, d float64) Color {
+	visibility := White
+	for i := 0; i < maxVisibilityCrossings; i++ {
+		hit, rec := hb.Hit(r, bias, d)
+		travelled := d
+		if hit {
+			travelled = rec.t
+		} else if math.IsInf(d, 1) {
+			travelled = 0
+			if inside, _, exit := hb.Box(0, 1).Clip(r, 0, math.MaxFloat64); inside {
+				travelled = exit
+			}
+		}
+		visibility = visibility.Mul(s.transmittance(r, travelled))
+		if !hit {
+			return visibility
+		}
+		if _, boundary := rec.Material.(MediumBoundary); !boundary && s.Resolve(r, rec) {
+			return Black
+		}
+		r, s = s.Cross(r, rec)
+		d -= rec.t
+	}
+	return Black
+}
+
+// transmittance returns the fraction of light of each channel making it along the ray r over the distance d
+// through the current medium, given at the wavelengths of r.
+func (s MediumStack) transmittance(r *geometry.Ray, d float64) Color {
+	switch m := s.Current().(type) {
+	case nil:
+		return White
+	case Participating:
+		return m.TransmittanceAlong(r, d)
+	default:
+		return Spectral(m.Transmittance(d), r)
+	}
+}
+
+// top returns the index of the entry of highest priority, or of the last entered among those of equal
+// priority, leaving out the entry at the index skip. Returns -1 when there is no such entry.
+func (s MediumStack) top(skip int) int {
+	top := -1
+	for i, e := range s.entries {
+		if i != skip && (top < 0 || e.priority >= s.entries[top].priority) {
+			top = i
+		}
+	}
+	return top
+}
+
+// find returns the index of the last entry matching e, or -1 when there is none.
+func (s MediumStack) find(e mediumEntry) int {
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		if f := s.entries[i]; f.priority == e.priority && f.refIndex == e.refIndex && sameMedium(f.medium, e.medium) {
+			return i
+		}
+	}
+	return -1
+}
+
+// sameMedium returns whether a and b are the same medium. Media of a type that cannot be compared, such as
+// structs holding slices, match any medium of the same type.
+func sameMedium(a Medium, b Medium) (same bool) {
+	defer func() {
+		// Comparing two values of the same type that cannot be compared panics.
+		if recover() != nil {
+			same = true
+		}
+	}()
+	return a == b
+}
+
+// boundedEntry returns the entry for the medium inside a material, or false if the material bounds no medium.
+func boundedEntry(m Material) (mediumEntry, bool) {
+	b := bounding(m)
+	if b == nil {
+		return mediumEntry{}, false
+	}
+	priority, refIndex := nesting(m)
+	return mediumEntry{medium: b.Interior(), priority: priority, refIndex: refIndex}, true
+}
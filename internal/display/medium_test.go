@@ -0,0 +1,143 @@
+package display
+
+import (
+	"testing"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+func TestMediumStack(t *testing.T) {
+	air := NewAbsorbing(NewColor(0.01, 0.01, 0.01))
+	tea := NewAbsorbing(NewColor(0.1, 0.5, 0.9))
+	glass := NewDielectric(1.5)
+	glass.Priority = 1
+	glass.Inside = NewAbsorbing(NewColor(0.2, 0.2, 0.2))
+	liquid := NewAbsorbingDielectric(1.33, tea)
+
+	// Rays travel along -Z through surfaces facing +Z.
+	down := geometry.NewRay(geometry.Vec{}, geometry.NewUnit(0, 0, -1), 0, nil)
+	up := geometry.NewRay(geometry.Vec{}, geometry.NewUnit(0, 0, 1), 0, nil)
+	enter := func(m Material) *HitRecord {
+		return &HitRecord{normal: geometry.NewUnit(0, 0, 1), Material: m}
+	}
+	leave := func(m Material) *HitRecord {
+		return &HitRecord{normal: geometry.NewUnit(0, 0, -1), Material: m}
+	}
+
+	tests := []struct {
+		name     string
+		rec      *HitRecord
+		out      *geometry.Ray
+		visible  bool
+		outerIOR float64
+		current  Medium
+	}{
+		{name: "into the glass", rec: enter(glass), out: down, visible: true, outerIOR: 1, current: glass.Inside},
+		{name: "liquid hidden in the glass", rec: enter(liquid), out: down, visible: false, current: glass.Inside},
+		{name: "out of the glass into the liquid", rec: leave(glass), out: down, visible: true, outerIOR: 1.33, current: tea},
+		{name: "reflected inside the liquid", rec: leave(liquid), out: up, visible: true, outerIOR: 1, current: tea},
+		{name: "out of the liquid", rec: leave(liquid), out: down, visible: true, outerIOR: 1, current: air},
+		{name: "out of a surface never entered", rec: leave(glass), out: down, visible: true, outerIOR: 1, current: air},
+	}
+	media := NewMediumStack(air)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			visible := media.Resolve(down, tt.rec)
+			if visible != tt.visible {
+				t.Fatalf("Resolve() = %v, want %v", visible, tt.visible)
+			}
+			if visible {
+				if tt.rec.outer() != tt.outerIOR {
+					t.Errorf("outer() = %v, want %v", tt.rec.outer(), tt.outerIOR)
+				}
+				media = media.Next(down, tt.rec, tt.out)
+			} else {
+				_, media = media.Cross(down, tt.rec)
+			}
+			if got := media.Current(); got != tt.current {
+				t.Errorf("Current() = %v, want %v", got, tt.current)
+			}
+		})
+	}
+}
+
+func TestMixNesting(t *testing.T) {
+	glass := NewDielectric(1.5)
+	glass.Priority = 2
+	glass.Inside = NewAbsorbing(NewColor(0.2, 0.2, 0.2))
+	dust := NewLambertian(NewSolid(White))
+	tests := []struct {
+		name     string
+		material Material
+	}{
+		{name: "mix", material: NewMix(dust, glass, NewGray(0.2))},
+		{name: "fresnel mix", material: NewFresnelMix(glass, dust, 1.5)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if b := bounding(tt.material); b == nil || b.Interior() != glass.Inside {
+				t.Errorf("Interior() is not the medium inside the glass")
+			}
+			if priority, refIndex := nesting(tt.material); priority != 2 || refIndex != 1.5 {
+				t.Errorf("nesting() = %v, %v, want 2, 1.5", priority, refIndex)
+			}
+		})
+	}
+}
+
+// layeredMedium is a Medium that cannot be compared with ==.
+type layeredMedium struct {
+	layers []float64
+}
+
+func (l layeredMedium) Transmittance(d float64) Color {
+	return White
+}
+
+func TestWrappedMedium(t *testing.T) {
+	glass := NewDielectric(1.5)
+	glass.Priority = 1
+	glass.Inside = layeredMedium{layers: []float64{0.2, 0.5}}
+	paint := NewLambertian(NewSolid(White))
+	tests := []struct {
+		name     string
+		material Material
+		bounded  bool
+	}{
+		{name: "lamp", material: NewEmissive(paint, NewSolid(White), 4), bounded: false},
+		{name: "glowing glass", material: NewEmissive(glass, NewSolid(White), 4), bounded: true},
+		{name: "normal-mapped paint", material: NewNormalMapped(paint, NewSolid(NewColor(0.5, 0.5, 1))), bounded: false},
+		{name: "bump-mapped glass", material: NewBumpMapped(glass, NewGray(0.5), 0.1), bounded: true},
+		{name: "varnished paint", material: NewLayered(paint, 1.5, 0), bounded: false},
+		{name: "iridescent paint", material: NewThinFilm(paint, 400, 1.33), bounded: false},
+		{name: "soap bubble", material: NewThinFilm(nil, 400, 1.33), bounded: false},
+		{name: "coated glass in a mix", material: NewMix(paint, NewThinFilm(glass, 400, 1.33), NewGray(0.5)), bounded: true},
+		{name: "mixed paints", material: NewFresnelMix(paint, paint, 1.5), bounded: false},
+	}
+	down := geometry.NewRay(geometry.Vec{}, geometry.NewUnit(0, 0, -1), 0, nil)
+	fog := NewMediumBoundary(NewAbsorbing(Black))
+	fog.Priority = 2
+	inFog := NewMediumStack(nil).Next(down, &HitRecord{normal: geometry.NewUnit(0, 0, 1), Material: fog}, down)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, bounded := boundedEntry(tt.material); bounded != tt.bounded {
+				t.Fatalf("boundedEntry() = %v, want %v", bounded, tt.bounded)
+			}
+			// Inside a medium of higher priority, only the surfaces bounding a medium of their own are hidden.
+			rec := &HitRecord{normal: geometry.NewUnit(0, 0, 1), Material: tt.material}
+			if visible := inFog.Resolve(down, rec); visible == tt.bounded {
+				t.Errorf("Resolve() = %v in the fog, want %v", visible, !tt.bounded)
+			}
+		})
+	}
+
+	// Media that cannot be compared are still left behind when crossing out of their surface.
+	media := NewMediumStack(nil).Next(down, &HitRecord{normal: geometry.NewUnit(0, 0, 1), Material: glass}, down)
+	if _, ok := media.Current().(layeredMedium); !ok {
+		t.Fatalf("Current() = %v inside the glass, want its medium", media.Current())
+	}
+	media = media.Next(down, &HitRecord{normal: geometry.NewUnit(0, 0, -1), Material: glass}, down)
+	if media.Current() != nil {
+		t.Errorf("Current() = %v out of the glass, want nil", media.Current())
+	}
+}
@@ -0,0 +1,217 @@
+package display
+
+import (
+	"math"
+	"math/cmplx"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+// The microfacet materials work in a local frame where the surface normal points along Z.
+// Directions in that frame point away from the surface, so the direction towards the viewer
+// is the inverse of the incoming ray.
+
+// ggx represents the Trowbridge-Reitz (GGX) distribution of microfacet normals, with a separate
+// roughness along each tangent of the surface.
+type ggx struct {
+	alphaX float64
+	alphaY float64
+}
+
+// newGGX returns a new ggx distribution from perceptual roughness values between 0 and 1.
+// The roughness is squared so that it feels linear to artists.
+func newGGX(roughnessX float64, roughnessY float64) ggx {
+	alpha := func(r float64) float64 {
+		return math.Max(clamp(r, 0, 1)*clamp(r, 0, 1), 0.0001)
+	}
+	return ggx{alphaX: alpha(roughnessX), alphaY: alpha(roughnessY)}
+}
+
+// smooth returns whether the surface is so smooth that it is better treated as a perfect mirror.
+func (g ggx) smooth() bool {
+	return math.Max(g.alphaX, g.alphaY) < 0.001
+}
+
+// d returns the density of microfacets oriented along the normal wm.
+func (g ggx) d(wm geometry.Vec) float64 {
+	cos2 := wm.Z * wm.Z
+	if cos2 == 0 {
+		return 0
+	}
+	e := (wm.X*wm.X/(g.alphaX*g.alphaX) + wm.Y*wm.Y/(g.alphaY*g.alphaY)) / cos2
+	return 1 / (math.Pi * g.alphaX * g.alphaY * cos2 * cos2 * (1 + e) * (1 + e))
+}
+
+// lambda returns the Smith auxiliary function, which measures the microfacet area masked in the direction w.
+func (g ggx) lambda(w geometry.Vec) float64 {
+	if w.Z == 0 {
+		return math.Inf(1)
+	}
+	alpha2Tan2 := (w.X*w.X*g.alphaX*g.alphaX + w.Y*w.Y*g.alphaY*g.alphaY) / (w.Z * w.Z)
+	return (math.Sqrt(1+alpha2Tan2) - 1) / 2
+}
+
+// g1 returns the fraction of microfacets visible from the direction w.
+func (g ggx) g1(w geometry.Vec) float64 {
+	return 1 / (1 + g.lambda(w))
+}
+
+// g2 returns the fraction of microfacets visible from both directions wo and wi.
+func (g ggx) g2(wo geometry.Vec, wi geometry.Vec) float64 {
+	return 1 / (1 + g.lambda(wo) + g.lambda(wi))
+}
+
+// visiblePDF returns the density of sampling the microfacet normal wm with sampleVisible from the direction w.
+func (g ggx) visiblePDF(w geometry.Vec, wm geometry.Vec) float64 {
+	if w.Z == 0 {
+		return 0
+	}
+	return g.g1(w) / math.Abs(w.Z) * g.d(wm) * math.Abs(w.Dot(wm))
+}
+
+// sampleVisible samples a microfacet normal visible from the direction w, following
+// "Sampling the GGX Distribution of Visible Normals" by Eric Heitz.
+func (g ggx) sampleVisible(w geometry.Vec, rnd geometry.Rnd) geometry.Vec {
+	// Transform the view direction to the hemisphere configuration.
+	vh := geometry.NewVec(g.alphaX*w.X, g.alphaY*w.Y, w.Z).ToUnit()
+	if vh.Z < 0 {
+		vh = vh.Inv()
+	}
+
+	// Build an orthonormal basis around it.
+	t1 := geometry.NewVec(1, 0, 0)
+	if lensq := vh.X*vh.X + vh.Y*vh.Y; lensq > 0 {
+		t1 = geometry.NewVec(-vh.Y, vh.X, 0).Scale(1 / math.Sqrt(lensq))
+	}
+	t2 := vh.Cross(t1)
+
+	// Sample the projected area of the visible hemisphere.
+	r := math.Sqrt(rnd.Float64())
+	phi := 2 * math.Pi * rnd.Float64()
+	p1 := r * math.Cos(phi)
+	p2 := r * math.Sin(phi)
+	s := 0.5 * (1 + vh.Z)
+	p2 = (1-s)*math.Sqrt(1-p1*p1) + s*p2
+
+	// Project back onto the hemisphere and undo the stretch.
+	nh := t1.Scale(p1).Add(t2.Scale(p2)).Add(vh.Scale(math.Sqrt(math.Max(0, 1-p1*p1-p2*p2))))
+	return geometry.NewVec(g.alphaX*nh.X, g.alphaY*nh.Y, math.Max(1e-6, nh.Z)).ToUnit().Vec
+}
+
+// sampleNormal samples a microfacet normal visible from the direction w, or returns the macro surface normal
+// when the surface is smooth.
+func (g ggx) sampleNormal(w geometry.Vec, rnd geometry.Rnd) geometry.Vec {
+	if g.smooth() {
+		return geometry.NewVec(0, 0, 1)
+	}
+	return g.sampleVisible(w, rnd)
+}
+
+// weight returns the attenuation left after scattering from wo to wi through a microfacet sampled with
+// sampleNormal. Sampling visible normals cancels out everything but the masking of the scattered direction.
+func (g ggx) weight(wo geometry.Vec, wi geometry.Vec) float64 {
+	if g.smooth() {
+		return 1
+	}
+	return g.g2(wo, wi) / g.g1(wo)
+}
+
+// reflection returns the microfacet normal reflecting wo into wi, along with the light reflected from wi
+// towards wo times the cosine of wi, leaving out the Fresnel factor, and the density with which wi is
+// sampled by reflecting wo off a normal from sampleNormal. Returns false when no rough microfacet
+// reflects wo into wi.
+func (g ggx) reflection(wo geometry.Vec, wi geometry.Vec) (geometry.Vec, float64, float64, bool) {
+	if g.smooth() || wo.Z <= 0 || wi.Z <= 0 {
+		return geometry.Vec{}, 0, 0, false
+	}
+	wm := wo.Add(wi)
+	if wm.LenSquared() < 1e-12 {
+		return geometry.Vec{}, 0, 0, false
+	}
+	wm = wm.ToUnit().Vec
+	return wm, g.d(wm) * g.g2(wo, wi) / (4 * wo.Z), g.visiblePDF(wo, wm) / (4 * wo.Dot(wm)), true
+}
+
+// refraction returns the microfacet normal refracting wo into wi on the far side of the surface, along with the
+// light refracted from wi towards wo times the cosine of wi, leaving out the Fresnel factor, and the density
+// with which wi is sampled by refracting wo through a normal from sampleNormal. eta is the ratio of the index of
+// refraction on the far side over the one on the near side. Returns false when no rough microfacet refracts wo
+// into wi.
+func (g ggx) refraction(wo geometry.Vec, wi geometry.Vec, eta float64) (geometry.Vec, float64, float64, bool) {
+	if g.smooth() || wo.Z <= 0 || wi.Z >= 0 {
+		return geometry.Vec{}, 0, 0, false
+	}
+	// The generalized half vector, turned to the near side.
+	wm := wo.Add(wi.Scale(eta))
+	if wm.LenSquared() < 1e-12 {
+		return geometry.Vec{}, 0, 0, false
+	}
+	wm = wm.ToUnit().Vec
+	if wm.Z < 0 {
+		wm = wm.Inv()
+	}
+	cosO, cosI := wo.Dot(wm), wi.Dot(wm)
+	if cosO <= 0 || cosI >= 0 {
+		return geometry.Vec{}, 0, 0, false
+	}
+	// The density of the refracted direction follows from the density of the normal.
+	denominator := cosO + eta*cosI
+	pdf := g.visiblePDF(wo, wm) * eta * eta * -cosI / (denominator * denominator)
+	return wm, pdf * g.weight(wo, wi), pdf, true
+}
+
+// localFrame returns a Basis around the normal of the surface on the side that the ray r comes from, with
+// X following the tangent along u, along with the direction towards the viewer in that basis and whether
+// the ray hit the back of the surface.
+func localFrame(r *geometry.Ray, rec *HitRecord) (geometry.Basis, geometry.Vec, bool) {
+	normal := rec.normal
+	back := r.Direction.Dot(normal) > 0
+	if back {
+		normal = normal.Inv()
+	}
+	basis := geometry.NewBasisFromTangent(normal, rec.dpdu)
+	return basis, basis.Local(r.Direction.Inv().Vec), back
+}
+
+// scatterLocal returns a ray scattered from the hit in the direction wi given in the basis.
+func scatterLocal(r *geometry.Ray, rec *HitRecord, basis geometry.Basis, wi geometry.Vec) *geometry.Ray {
+	return geometry.NewRay(rec.p, basis.World(wi).ToUnit(), r.Time, r.Rnd)
+}
+
+// schlickColor calculates Schlick's approximation of the Fresnel factor for a reflectance f0 at normal incidence.
+func schlickColor(f0 Color, cos float64) Color {
+	weight := math.Pow(1-clamp(cos, 0, 1), 5)
+	return f0.Scale(1 - weight).Add(White.Scale(weight))
+}
+
+// reflect returns the direction w mirrored about the normal n, both pointing away from the surface.
+func reflect(w geometry.Vec, n geometry.Vec) geometry.Vec {
+	return n.Scale(2 * w.Dot(n)).Sub(w)
+}
+
+// fresnelConductor returns the fraction of light reflected by a conductor with the complex index of
+// refraction eta + ik, for light arriving at an angle whose cosine is cosI.
+func fresnelConductor(cosI float64, eta float64, k float64) float64 {
+	cosI = clamp(cosI, 0, 1)
+	n := complex(eta, k)
+	ci := complex(cosI, 0)
+	sin2T := complex(1-cosI*cosI, 0) / (n * n)
+	cosT := cmplx.Sqrt(1 - sin2T)
+	parallel := (n*ci - cosT) / (n*ci + cosT)
+	perpendicular := (ci - n*cosT) / (ci + n*cosT)
+	return (norm(parallel) + norm(perpendicular)) / 2
+}
+
+// fresnelConductorColor returns the fraction of light reflected by a conductor for each channel.
+func fresnelConductorColor(cosI float64, eta Color, k Color) Color {
+	return NewColor(
+		fresnelConductor(cosI, eta.X, k.X),
+		fresnelConductor(cosI, eta.Y, k.Y),
+		fresnelConductor(cosI, eta.Z, k.Z),
+	)
+}
+
+// norm returns the squared magnitude of a complex number.
+func norm(c complex128) float64 {
+	return real(c)*real(c) + imag(c)*imag(c)
+}
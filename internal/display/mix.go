@@ -0,0 +1,100 @@
+package display
+
+import (
+	"math"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+// Mix represents a blend of two materials varying over a surface, such as rust over metal or moss over stone.
+//
+// Weight is read as a grayscale value: where it is 0 the surface is made of A and where it is 1 of B. In between,
+// each ray picks one of the materials at random in proportion to the weight, which averages out to a blend.
+type Mix struct {
+	A      Material
+	B      Material
+	Weight Texture
+}
+
+// NewMix creates a new Mix of two materials, following a weight texture.
+func NewMix(a Material, b Material, weight Texture) Mix {
+	return Mix{A: a, B: b, Weight: weight}
+}
+
+// Scatter scatters light rays off one of the materials, picked at random in proportion to the weight.
+func (m Mix) Scatter(r *geometry.Ray, rec *HitRecord) (bool, *Color, *geometry.Ray) {
+	if r.Rnd.Float64() < intensity(m.Weight, rec) {
+		return m.B.Scatter(r, rec)
+	}
+	return m.A.Scatter(r, rec)
+}
+
+// Emit returns the blend of the light emitted by both materials.
+func (m Mix) Emit(r *geometry.Ray, rec *HitRecord) Color {
+	return blend(m.A.Emit(r, rec), m.B.Emit(r, rec), intensity(m.Weight, rec))
+}
+
+// Evaluate returns the blend of the light scattered from wi by both materials.
+func (m Mix) Evaluate(r *geometry.Ray, rec *HitRecord, wi geometry.Unit) (Color, float64) {
+	return mixEvaluate(m.A, m.B, intensity(m.Weight, rec), r, rec, wi)
+}
+
+// wrapped returns the blended materials.
+func (m Mix) wrapped() []Material {
+	return []Material{m.A, m.B}
+}
+
+// FresnelMix represents a blend of two materials that changes with the angle the surface is seen at,
+// following the reflectance of a dielectric with the given index of refraction. Facing is seen when looking
+// at the surface head on and Grazing towards its edges, such as a sheen of dust or a glossy edge.
+type FresnelMix struct {
+	Facing   Material
+	Grazing  Material
+	RefIndex float64
+}
+
+// NewFresnelMix creates a new FresnelMix of two materials with a given index of refraction.
+func NewFresnelMix(facing Material, grazing Material, refIndex float64) FresnelMix {
+	return FresnelMix{Facing: facing, Grazing: grazing, RefIndex: refIndex}
+}
+
+// Scatter scatters light rays off one of the materials, picked at random in proportion to the reflectance.
+func (f FresnelMix) Scatter(r *geometry.Ray, rec *HitRecord) (bool, *Color, *geometry.Ray) {
+	if r.Rnd.Float64() < f.weight(r, rec) {
+		return f.Grazing.Scatter(r, rec)
+	}
+	return f.Facing.Scatter(r, rec)
+}
+
+// Emit returns the blend of the light emitted by both materials.
+func (f FresnelMix) Emit(r *geometry.Ray, rec *HitRecord) Color {
+	return blend(f.Facing.Emit(r, rec), f.Grazing.Emit(r, rec), f.weight(r, rec))
+}
+
+// Evaluate returns the blend of the light scattered from wi by both materials.
+func (f FresnelMix) Evaluate(r *geometry.Ray, rec *HitRecord, wi geometry.Unit) (Color, float64) {
+	return mixEvaluate(f.Facing, f.Grazing, f.weight(r, rec), r, rec, wi)
+}
+
+// wrapped returns the blended materials.
+func (f FresnelMix) wrapped() []Material {
+	return []Material{f.Facing, f.Grazing}
+}
+
+// weight returns the share of the grazing material for the ray r.
+func (f FresnelMix) weight(r *geometry.Ray, rec *HitRecord) float64 {
+	return fresnelDielectric(math.Abs(r.Direction.Dot(rec.normal)), f.RefIndex)
+}
+
+// blend returns the linear interpolation between the colors a and b by the weight w.
+func blend(a Color, b Color, w float64) Color {
+	return a.Scale(1 - w).Add(b.Scale(w))
+}
+
+// mixEvaluate returns the light scattered from wi by the materials a and b blended by the weight w, and the
+// density of sampling wi by picking either material at random in proportion to the weight.
+func mixEvaluate(a Material, b Material, w float64, r *geometry.Ray, rec *HitRecord, wi geometry.Unit) (Color, float64) {
+	valueA, pdfA := evaluate(a, r, rec, wi)
+	valueB, pdfB := evaluate(b, r, rec, wi)
+	return blend(valueA, valueB, w), lerp(pdfA, pdfB, w)
+}
@@ -0,0 +1,167 @@
+package display
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LoadMTL reads the materials of a Wavefront .mtl file as Principled materials, keyed by name.
+// Texture maps are opened relative to the directory of the file.
+func LoadMTL(path string) (map[string]Principled, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	dir := filepath.Dir(path)
+	return parseMTL(f, func(name string) (io.ReadCloser, error) {
+		return os.Open(filepath.Join(dir, name))
+	})
+}
+
+// parseMTL reads the materials of a .mtl file, using open to read the images of texture maps.
+//
+// Besides the diffuse color Kd, the transmission filter Tf, the index of refraction Ni and the opacity
+// given by the dissolve d (or its inverse Tr), the PBR extension statements Pr (roughness), Pm (metallic), Ps (sheen), Pc (clearcoat),
+// Pcr (clearcoat roughness) and Ke (emission) are understood, along with their map_ variants.
+// Other statements, such as the Phong parameters Ka, Ks and Ns, are ignored.
+func parseMTL(r io.Reader, open func(name string) (io.ReadCloser, error)) (map[string]Principled, error) {
+	materials := map[string]Principled{}
+	var name string
+	var current *Principled
+	save := func() {
+		if current != nil {
+			materials[name] = *current
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		keyword, args := fields[0], fields[1:]
+		if keyword == "newmtl" {
+			if len(args) == 0 {
+				return nil, fmt.Errorf("line %d: newmtl without a name", line)
+			}
+			save()
+			name = strings.Join(args, " ")
+			p := NewPrincipled(NewSolid(White), 0, 1)
+			current = &p
+			continue
+		}
+		if current == nil {
+			return nil, fmt.Errorf("line %d: %s before newmtl", line, keyword)
+		}
+
+		if strings.HasPrefix(keyword, "map_") {
+			if len(args) == 0 {
+				return nil, fmt.Errorf("line %d: %s without a file", line, keyword)
+			}
+			target := mtlTexture(current, strings.TrimPrefix(keyword, "map_"))
+			if target == nil {
+				continue
+			}
+			// Options such as -bm come before the file name.
+			rc, err := open(args[len(args)-1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			img, err := NewImage(rc)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			*target = img
+			continue
+		}
+
+		switch keyword {
+		case "Kd", "Ke", "Tf":
+			if len(args) > 0 && (args[0] == "spectral" || args[0] == "xyz") {
+				// Colors given as spectral curves or in CIE XYZ are ignored.
+				continue
+			}
+			values, err := mtlFloats(args)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			if len(values) != 1 && len(values) != 3 {
+				return nil, fmt.Errorf("line %d: %s needs 1 or 3 values", line, keyword)
+			}
+			c := NewColor(values[0], values[0], values[0])
+			if len(values) == 3 {
+				c = NewColor(values[0], values[1], values[2])
+			}
+			*mtlTexture(current, keyword) = NewSolid(c)
+		case "Ni", "d", "Tr", "Pr", "Pm", "Ps", "Pc", "Pcr":
+			values, err := mtlFloats(args)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			if len(values) != 1 {
+				return nil, fmt.Errorf("line %d: %s needs 1 value", line, keyword)
+			}
+			v := values[0]
+			switch keyword {
+			case "Ni":
+				current.RefIndex = v
+			case "Tr":
+				current.Alpha = NewGray(1 - v)
+			default:
+				*mtlTexture(current, keyword) = NewGray(v)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	save()
+	return materials, nil
+}
+
+// mtlFloats parses the arguments of a .mtl statement as numbers.
+func mtlFloats(args []string) ([]float64, error) {
+	values := make([]float64, len(args))
+	for i, arg := range args {
+		v, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// mtlTexture returns the parameter of a Principled material set by a .mtl statement, or nil when
+// the statement does not map onto a parameter.
+func mtlTexture(p *Principled, keyword string) *Texture {
+	switch keyword {
+	case "Kd":
+		return &p.BaseColor
+	case "Ke":
+		return &p.Emission
+	case "Tf":
+		return &p.Transmission
+	case "d":
+		return &p.Alpha
+	case "Pr":
+		return &p.Roughness
+	case "Pm":
+		return &p.Metallic
+	case "Ps":
+		return &p.Sheen
+	case "Pc":
+		return &p.Clearcoat
+	case "Pcr":
+		return &p.ClearcoatRoughness
+	default:
+		return nil
+	}
+}
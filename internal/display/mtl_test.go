@@ -0,0 +1,92 @@
+package display
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func noFiles(name string) (io.ReadCloser, error) {
+	return nil, errors.New("no file " + name)
+}
+
+func TestParseMTL(t *testing.T) {
+	src := `
+# exported materials
+newmtl paint
+Kd 0.8 0.1 0.1
+Ks 0.5 0.5 0.5
+Pr 0.4
+Pm 0
+Pc 1
+Pcr 0.1
+illum 2
+bump -bm 1 paint_bump.png
+norm paint_normal.png
+disp paint_height.png
+refl -type sphere studio.png
+Kd spectral paint.rfl
+Tf xyz 0.9 0.9 0.9
+
+newmtl glass
+Kd 1 1 1
+Tf 0.75
+Ni 1.45
+
+newmtl veil
+d 0.25
+
+newmtl screen
+Tr 0.4
+
+newmtl lamp
+Ke 4
+`
+	materials, err := parseMTL(strings.NewReader(src), noFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(materials) != 5 {
+		t.Fatalf("got %d materials, want 5", len(materials))
+	}
+	rec := &HitRecord{}
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"paint roughness", intensity(materials["paint"].Roughness, rec), 0.4},
+		{"paint clearcoat", intensity(materials["paint"].Clearcoat, rec), 1},
+		{"paint clearcoat roughness", intensity(materials["paint"].ClearcoatRoughness, rec), 0.1},
+		{"paint base red", materials["paint"].BaseColor.At(0, 0, rec.p).Red(), 0.8},
+		{"glass transmission", intensity(materials["glass"].Transmission, rec), 0.75},
+		{"glass index", materials["glass"].RefIndex, 1.45},
+		{"veil alpha", intensity(materials["veil"].Alpha, rec), 0.25},
+		{"veil transmission", intensity(materials["veil"].Transmission, rec), 0},
+		{"screen alpha", intensity(materials["screen"].Alpha, rec), 0.6},
+		{"lamp emission", intensity(materials["lamp"].Emission, rec), 4},
+		{"lamp roughness", intensity(materials["lamp"].Roughness, rec), 1},
+	}
+	for _, tt := range tests {
+		if diff := tt.got - tt.want; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestParseMTLErrors(t *testing.T) {
+	tests := map[string]string{
+		"statement before newmtl": "Kd 1 1 1",
+		"bad number":              "newmtl a\nPr rough",
+		"wrong arity":             "newmtl a\nKd 1 1",
+		"missing map":             "newmtl a\nmap_Kd albedo.png",
+	}
+	for name, src := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseMTL(strings.NewReader(src), noFiles); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package display
+
+import (
+	"math"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+// Participating is implemented by a Medium that scatters or emits light as it travels through it, such as
+// fog, smoke or fire, rather than only absorbing it.
+type Participating interface {
+	Medium
+	// Interact follows a ray r through the medium until it scatters, or reaches the next surface at the
+	// distance dMax. Returns the HitRecord of the point where the ray scattered, or nil when it reached dMax,
+	// the weight of the light arriving there, and the light emitted by the medium along the way.
+	// Colors are given at the wavelengths of r when rendering spectrally.
+	Interact(r *geometry.Ray, dMax float64) (*HitRecord, Color, Color)
+	// TransmittanceAlong returns an estimate of the fraction of light of each channel making it along a ray r
+	// over the distance d without being scattered or absorbed, given at the wavelengths of r.
+	TransmittanceAlong(r *geometry.Ray, d float64) Color
+}
+
+// ScatteringMedium represents a Participating medium absorbing, scattering and emitting light by amounts that
+// may differ per channel, such as coloured smoke, milky water or fire.
+//
+// Absorption and Scattering give the fraction of light of each channel absorbed and scattered per unit of
+// distance, and Emission the light emitted per unit of distance. All three are scaled by the Density field
+// where one is set. Light is scattered following the Phase function.
+//
+// When a Temperature field in kelvin is set, the medium glows with the color of a black body at the
+// temperature of each point, such as the output of a fire simulation. The hottest parts of the field glow
+// with the given Emission, and the cooler parts fade to a dim red.
+type ScatteringMedium struct {
+	Absorption  Color
+	Scattering  Color
+	Emission    Color
+	Phase       Phase
+	Density     DensityField
+	Temperature DensityField
+}
+
+// NewScatteringMedium returns a new ScatteringMedium of constant density, scattering light following a
+// HenyeyGreenstein with the given asymmetry.
+func NewScatteringMedium(absorption Color, scattering Color, g float64) *ScatteringMedium {
+	return &ScatteringMedium{Absorption: absorption, Scattering: scattering, Phase: NewHenyeyGreenstein(g)}
+}
+
+// NewFire returns a new ScatteringMedium of dark soot following a density field, glowing with the colors
+// of a temperature field in kelvin.
+func NewFire(density DensityField, temperature DensityField, emission float64) *ScatteringMedium {
+	return &ScatteringMedium{
+		Absorption:  NewColor(1, 1, 1),
+		Scattering:  NewColor(0.1, 0.1, 0.1),
+		Emission:    White.Scale(emission),
+		Phase:       UniformPhase{},
+		Density:     density,
+		Temperature: temperature,
+	}
+}
+
+// Transmittance returns the fraction of light of each channel left after a distance d through the medium,
+// where the density is 1.
+func (m *ScatteringMedium) Transmittance(d float64) Color {
+	return NewAbsorbing(m.Absorption.Add(m.Scattering)).Transmittance(d)
+}
+
+// Interact follows a ray through the medium with spectral tracking, from "Spectral and Decomposition Tracking
+// for Rendering Heterogeneous Volumes" by Kutz et al.
+//
+// Tentative collisions are sampled against a majorant bounding the extinction of all channels. At each one,
+// the light emitted there is gathered, and the ray either scatters or carries on through fictitious matter
+// making up the difference to the majorant, picked in proportion to the light each would carry. The weight
+// of the ray accounts for the channels where these chances differ from the actual coefficients.
+func (m *ScatteringMedium) Interact(r *geometry.Ray, dMax float64) (*HitRecord, Color, Color) {
+	sigmaS := Spectral(m.Scattering, r)
+	sigmaT := Spectral(m.Absorption, r).Add(sigmaS)
+	majorant := math.Max(sigmaT.Red(), math.Max(sigmaT.Green(), sigmaT.Blue()))
+	if m.Density != nil {
+		majorant *= m.Density.Majorant()
+	}
+	if majorant <= 0 {
+		return nil, White, Black
+	}
+	emitted, weight := Black, White
+	d := 0.0
+	for i := 0; i < maxTrackingSteps; i++ {
+		d -= math.Log(1-r.Rnd.Float64()) / majorant
+		if d >= dMax {
+			return nil, weight, emitted
+		}
+		p := r.At(d)
+		density := 1.0
+		if m.Density != nil {
+			density = m.Density.Density(p)
+		}
+		emitted = emitted.Add(weight.Mul(m.emission(r, p, density)).Scale(1 / majorant))
+
+		scattering := sigmaS.Scale(density)
+		null := NewColor(majorant-density*sigmaT.Red(), majorant-density*sigmaT.Green(), majorant-density*sigmaT.Blue())
+		ps := weight.Mul(scattering).Vec.Dot(geometry.NewVec(1, 1, 1))
+		pn := weight.Mul(null).Vec.Dot(geometry.NewVec(1, 1, 1))
+		if ps+pn <= 0 {
+			// All the light was absorbed.
+			return nil, Black, emitted
+		}
+		if r.Rnd.Float64()*(ps+pn) < ps {
+			weight = weight.Mul(scattering).Scale((ps + pn) / (ps * majorant))
+			return scatteringEvent(r, d, &Anisotropic{Albedo: NewSolid(White), Phase: m.Phase}), weight, emitted
+		}
+		weight = weight.Mul(null).Scale((ps + pn) / (pn * majorant))
+	}
+	return nil, Black, emitted
+}
+
+// TransmittanceAlong estimates the light making it through the medium with ratio tracking, or exactly where
+// the density is constant.
+func (m *ScatteringMedium) TransmittanceAlong(r *geometry.Ray, d float64) Color {
+	sigmaT := Spectral(m.Absorption, r).Add(Spectral(m.Scattering, r))
+	if m.Density == nil {
+		return NewAbsorbing(sigmaT).Transmittance(d)
+	}
+	majorant := math.Max(sigmaT.Red(), math.Max(sigmaT.Green(), sigmaT.Blue())) * m.Density.Majorant()
+	if majorant <= 0 {
+		return White
+	}
+	transmittance := White
+	t := 0.0
+	for i := 0; i < maxTrackingSteps && transmittance != Black; i++ {
+		t -= math.Log(1-r.Rnd.Float64()) / majorant
+		if t >= d {
+			break
+		}
+		density := m.Density.Density(r.At(t))
+		transmittance = transmittance.Mul(NewColor(
+			math.Max(0, 1-density*sigmaT.Red()/majorant),
+			math.Max(0, 1-density*sigmaT.Green()/majorant),
+			math.Max(0, 1-density*sigmaT.Blue()/majorant),
+		))
+	}
+	return transmittance
+}
+
+// emission returns the light emitted per unit of distance at the point p of a ray r, where the medium has
+// the given density.
+func (m *ScatteringMedium) emission(r *geometry.Ray, p geometry.Vec, density float64) Color {
+	if m.Emission == Black || density == 0 {
+		return Black
+	}
+	emission := m.Emission.Scale(density)
+	if m.Temperature != nil {
+		hottest := blackbodyGlow(m.Temperature.Majorant())
+		luminance := hottest.Luminance()
+		if luminance <= 0 {
+			return Black
+		}
+		emission = emission.Mul(blackbodyGlow(m.Temperature.Density(p))).Scale(1 / luminance)
+	}
+	return Spectral(emission, r)
+}
+
+// MediumBoundary represents the invisible surface of a region filled with a Medium, such as a room full of
+// fog, which light crosses without being reflected or refracted.
+type MediumBoundary struct {
+	Medium   Medium
+	Priority int // the priority of the medium where it overlaps others, see Nested
+	nonEmitter
+}
+
+// NewMediumBoundary returns a new MediumBoundary enclosing a medium.
+func NewMediumBoundary(medium Medium) MediumBoundary {
+	return MediumBoundary{Medium: medium}
+}
+
+// Scatter lets light rays carry on in the same direction.
+func (b MediumBoundary) Scatter(r *geometry.Ray, rec *HitRecord) (bool, *Color, *geometry.Ray) {
+	return true, &White, geometry.NewRay(rec.p, r.Direction, r.Time, r.Rnd)
+}
+
+// Interior returns the medium enclosed by the surface.
+func (b MediumBoundary) Interior() Medium {
+	return b.Medium
+}
+
+// Nesting returns the priority of the medium, which does not refract light.
+func (b MediumBoundary) Nesting() (int, float64) {
+	return b.Priority, 1
+}
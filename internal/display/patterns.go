@@ -37,6 +37,11 @@ func NewSolid(color Color) Solid {
 	return Solid{Color: color}
 }
 
+// NewGray returns a new Solid of a shade of gray, for textures used as grayscale maps.
+func NewGray(value float64) Solid {
+	return NewSolid(NewColor(value, value, value))
+}
+
 // At returns the Color of the ray.
 func (s Solid) At(u float64, v float64, p geometry.Vec) Color {
 	return s.Color
@@ -0,0 +1,130 @@
+package display
+
+import (
+	"math"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+// Phase represents a phase function, which describes how light is scattered in each direction by a
+// participating medium such as fog, smoke or a cloud.
+//
+// Directions are given as the direction light travels in, so the angle between dir and out is 0 when light
+// carries on straight ahead.
+type Phase interface {
+	// Sample returns a direction to scatter light travelling in the direction dir into.
+	Sample(dir geometry.Unit, rnd geometry.Rnd) geometry.Unit
+	// PDF returns the probability density of Sample scattering light travelling in the direction dir into out.
+	PDF(dir geometry.Unit, out geometry.Unit) float64
+}
+
+// UniformPhase represents a Phase scattering light equally in all directions.
+type UniformPhase struct{}
+
+// Sample returns a random direction, spread evenly over the unit sphere.
+func (UniformPhase) Sample(dir geometry.Unit, rnd geometry.Rnd) geometry.Unit {
+	z := 1 - 2*rnd.Float64()
+	r := math.Sqrt(math.Max(0, 1-z*z))
+	phi := 2 * math.Pi * rnd.Float64()
+	return geometry.NewUnit(r*math.Cos(phi), r*math.Sin(phi), z)
+}
+
+// PDF returns one over the area of the unit sphere.
+func (UniformPhase) PDF(dir geometry.Unit, out geometry.Unit) float64 {
+	return 1 / (4 * math.Pi)
+}
+
+// HenyeyGreenstein represents the Phase of a medium scattering light mostly forwards or backwards.
+//
+// G is the average cosine of the scattering angle, from -1 where all the light is scattered back, through 0
+// where light is scattered equally in all directions, to 1 where it all carries on straight ahead. Fog and
+// clouds scatter light strongly forwards, with a G around 0.8 or more.
+type HenyeyGreenstein struct {
+	G float64
+}
+
+// NewHenyeyGreenstein returns a new HenyeyGreenstein with the given asymmetry.
+func NewHenyeyGreenstein(g float64) HenyeyGreenstein {
+	return HenyeyGreenstein{G: clamp(g, -0.999, 0.999)}
+}
+
+// Sample returns a direction distributed following the phase function around dir.
+func (h HenyeyGreenstein) Sample(dir geometry.Unit, rnd geometry.Rnd) geometry.Unit {
+	g := h.G
+	var cos float64
+	if math.Abs(g) < 1e-3 {
+		cos = 1 - 2*rnd.Float64()
+	} else {
+		s := (1 - g*g) / (1 - g + 2*g*rnd.Float64())
+		cos = (1 + g*g - s*s) / (2 * g)
+	}
+	cos = clamp(cos, -1, 1)
+	sin := math.Sqrt(1 - cos*cos)
+	phi := 2 * math.Pi * rnd.Float64()
+	return geometry.NewBasis(dir).World(geometry.NewVec(sin*math.Cos(phi), sin*math.Sin(phi), cos)).ToUnit()
+}
+
+// PDF returns the value of the phase function for light travelling in the direction dir scattered into out.
+func (h HenyeyGreenstein) PDF(dir geometry.Unit, out geometry.Unit) float64 {
+	g := h.G
+	denom := 1 + g*g - 2*g*dir.Dot(out)
+	return (1 - g*g) / (4 * math.Pi * denom * math.Sqrt(denom))
+}
+
+// DoubleHenyeyGreenstein represents the Phase of a medium with both a forward and a backward scattering peak,
+// such as a cloud, which glows around the sun but also shows a faint glory opposite it.
+// Blend is the share of light scattered following Backward, the rest following Forward.
+type DoubleHenyeyGreenstein struct {
+	Forward  HenyeyGreenstein
+	Backward HenyeyGreenstein
+	Blend    float64
+}
+
+// NewDoubleHenyeyGreenstein returns a new DoubleHenyeyGreenstein blending two asymmetries.
+func NewDoubleHenyeyGreenstein(forward float64, backward float64, blend float64) DoubleHenyeyGreenstein {
+	return DoubleHenyeyGreenstein{
+		Forward:  NewHenyeyGreenstein(forward),
+		Backward: NewHenyeyGreenstein(backward),
+		Blend:    clamp(blend, 0, 1),
+	}
+}
+
+// Sample returns a direction following one of the two lobes, picked at random in proportion to the blend.
+func (d DoubleHenyeyGreenstein) Sample(dir geometry.Unit, rnd geometry.Rnd) geometry.Unit {
+	if rnd.Float64() < d.Blend {
+		return d.Backward.Sample(dir, rnd)
+	}
+	return d.Forward.Sample(dir, rnd)
+}
+
+// PDF returns the blend of the values of both lobes.
+func (d DoubleHenyeyGreenstein) PDF(dir geometry.Unit, out geometry.Unit) float64 {
+	return lerp(d.Forward.PDF(dir, out), d.Backward.PDF(dir, out), d.Blend)
+}
+
+// Anisotropic represents the material of a participating medium, scattering light in directions following
+// a Phase function.
+type Anisotropic struct {
+	Albedo Texture
+	Phase  Phase
+	nonEmitter
+}
+
+// NewAnisotropic returns a new Anisotropic scattering light following a HenyeyGreenstein with the
+// given asymmetry.
+func NewAnisotropic(albedo Texture, g float64) *Anisotropic {
+	return &Anisotropic{Albedo: albedo, Phase: NewHenyeyGreenstein(g)}
+}
+
+// Scatter scatters light in a direction sampled from the phase function.
+// As the phase function is sampled exactly, the light is only tinted by the albedo.
+func (a *Anisotropic) Scatter(r *geometry.Ray, rec *HitRecord) (bool, *Color, *geometry.Ray) {
+	color := a.Albedo.At(rec.u, rec.v, rec.p)
+	return true, &color, spread(geometry.NewRay(rec.p, a.Phase.Sample(r.Direction, r.Rnd), r.Time, r.Rnd))
+}
+
+// Evaluate returns the light scattered from wi, following the phase function.
+func (a *Anisotropic) Evaluate(r *geometry.Ray, rec *HitRecord, wi geometry.Unit) (Color, float64) {
+	pdf := a.Phase.PDF(r.Direction, wi)
+	return a.Albedo.At(rec.u, rec.v, rec.p).Scale(pdf), pdf
+}
@@ -0,0 +1,93 @@
+package display
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+func TestPhase(t *testing.T) {
+	tests := []struct {
+		name    string
+		phase   Phase
+		meanCos float64
+	}{
+		{
+			name:    "uniform",
+			phase:   UniformPhase{},
+			meanCos: 0,
+		},
+		{
+			name:    "forward",
+			phase:   NewHenyeyGreenstein(0.8),
+			meanCos: 0.8,
+		},
+		{
+			name:    "backward",
+			phase:   NewHenyeyGreenstein(-0.3),
+			meanCos: -0.3,
+		},
+		{
+			name:    "double",
+			phase:   NewDoubleHenyeyGreenstein(0.9, -0.5, 0.25),
+			meanCos: 0.75*0.9 - 0.25*0.5,
+		},
+	}
+	dir := geometry.NewVec(1, 2, -2).ToUnit()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The PDF integrates to one over the sphere.
+			const steps = 20000
+			var integral float64
+			for i := 0; i < steps; i++ {
+				cos := -1 + (float64(i)+0.5)*2/steps
+				out := geometry.NewBasis(dir).World(geometry.NewVec(math.Sqrt(1-cos*cos), 0, cos)).ToUnit()
+				integral += tt.phase.PDF(dir, out) * 2 * math.Pi * 2 / steps
+			}
+			if math.Abs(integral-1) > 1e-3 {
+				t.Errorf("PDF integrates to %v, want 1", integral)
+			}
+
+			// Samples follow the asymmetry.
+			rnd := rand.New(rand.NewSource(1))
+			const samples = 100000
+			var sum float64
+			for i := 0; i < samples; i++ {
+				sum += dir.Dot(tt.phase.Sample(dir, rnd))
+			}
+			if got := sum / samples; math.Abs(got-tt.meanCos) > 0.02 {
+				t.Errorf("mean cosine = %v, want %v", got, tt.meanCos)
+			}
+
+			// Samples fall in each cell of a grid over the sphere around dir as often as the PDF predicts.
+			const cosCells, phiCells = 10, 8
+			basis := geometry.NewBasis(dir)
+			var counts [cosCells][phiCells]float64
+			for i := 0; i < samples; i++ {
+				local := basis.Local(tt.phase.Sample(dir, rnd).Vec)
+				c := clampIndex(int((local.Z+1)/2*cosCells), cosCells-1)
+				phi := math.Atan2(local.Y, local.X) + math.Pi
+				p := clampIndex(int(phi/(2*math.Pi)*phiCells), phiCells-1)
+				counts[c][p]++
+			}
+			for c := 0; c < cosCells; c++ {
+				// The share of the samples expected in a cell, integrating the PDF across its band of cosines.
+				const substeps = 100
+				var share float64
+				for k := 0; k < substeps; k++ {
+					cos := -1 + (float64(c)+(float64(k)+0.5)/substeps)*2/cosCells
+					out := basis.World(geometry.NewVec(math.Sqrt(1-cos*cos), 0, cos)).ToUnit()
+					share += tt.phase.PDF(dir, out) * 2 * math.Pi * 2 / (cosCells * substeps) / phiCells
+				}
+				want := share * samples
+				for p := 0; p < phiCells; p++ {
+					if got := counts[c][p]; math.Abs(got-want) > 5*math.Sqrt(want)+1 {
+						t.Errorf("cell %d, %d has %v samples, want %.0f", c, p, got, want)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package display
+
+import (
+	"math"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+// Principled represents an all-purpose material in the spirit of the Disney principled BSDF, whose parameters
+// can each be driven by a Texture. Grayscale parameters read the average of the texture's channels and range
+// from 0 to 1; a nil texture sets the parameter to 0.
+//
+// The material is built from layered lobes, each picked at random in proportion to the light it reflects:
+//   - a clearcoat, a smooth varnish with an index of refraction of 1.5 on top of everything else,
+//   - a metal, reflecting the base color with a Fresnel tint at grazing angles,
+//   - a dielectric specular reflection, whose strength is set by Specular,
+//   - a transmission, refracting light tinted by the base color into the material,
+//   - a diffuse base, tinted towards white at grazing angles by the sheen.
+//
+// Light not taken by one layer is passed on to the layers below, so the material never reflects more light
+// than it receives.
+//
+// Alpha is the opacity of the surface, as read from a .mtl file. The material itself is always opaque: the
+// Alpha is meant as the Mask of a Cutout around the surfaces using it, and unlike the other parameters a nil
+// Alpha stands for a fully opaque surface.
+type Principled struct {
+	BaseColor          Texture
+	Metallic           Texture
+	Roughness          Texture
+	Specular           Texture // the strength of the specular reflection, where 0.5 matches common dielectrics
+	Sheen              Texture
+	Clearcoat          Texture
+	ClearcoatRoughness Texture
+	Transmission       Texture
+	Emission           Texture
+	Alpha              Texture
+	RefIndex           float64 // the index of refraction used for the transmission
+}
+
+// NewPrincipled creates a new Principled material with a given base color, metallic and roughness,
+// and the specular reflection of common dielectrics.
+func NewPrincipled(baseColor Texture, metallic float64, roughness float64) Principled {
+	return Principled{
+		BaseColor: baseColor,
+		Metallic:  NewGray(metallic),
+		Roughness: NewGray(roughness),
+		Specular:  NewGray(0.5),
+		RefIndex:  1.5,
+	}
+}
+
+// Emit returns the Emission of the material at the coordinates of the given HitRecord.
+func (p Principled) Emit(r *geometry.Ray, rec *HitRecord) Color {
+	if p.Emission == nil {
+		return Black
+	}
+	return p.Emission.At(rec.u, rec.v, rec.p)
+}
+
+// Scatter scatters light rays off one of the lobes of the material.
+func (p Principled) Scatter(r *geometry.Ray, rec *HitRecord) (bool, *Color, *geometry.Ray) {
+	basis, wo, back := localFrame(r, rec)
+	roughness := intensity(p.Roughness, rec)
+	dist := newGGX(roughness, roughness)
+	transmission := intensity(p.Transmission, rec)
+
+	if back && transmission > 0 {
+		// Rays refracted into the material leave it through a plain rough interface.
+		wi, weight, ok := scatterDielectric(wo, dist, 1/p.RefIndex, r.Rnd)
+		if !ok {
+			return false, &Color{}, &geometry.Ray{}
+		}
+		attenuation := White.Scale(weight)
+		scattered := scatterLocal(r, rec, basis, wi)
+		scattered.Spread = !dist.smooth()
+		return true, &attenuation, scattered
+	}
+
+	base := White
+	if p.BaseColor != nil {
+		base = p.BaseColor.At(rec.u, rec.v, rec.p)
+	}
+
+	// reflectOff reflects the ray off a microfacet sampled from dist, tinted by the Fresnel factor of the lobe.
+	reflectOff := func(dist ggx, fresnel func(cos float64) Color) (bool, *Color, *geometry.Ray) {
+		wm := dist.sampleNormal(wo, r.Rnd)
+		wi := reflect(wo, wm)
+		if wi.Z <= 0 {
+			return false, &Color{}, &geometry.Ray{}
+		}
+		attenuation := fresnel(wo.Dot(wm)).Scale(dist.weight(wo, wi))
+		scattered := scatterLocal(r, rec, basis, wi)
+		scattered.Spread = !dist.smooth()
+		return true, &attenuation, scattered
+	}
+	// The dielectric lobes are picked in proportion to their Fresnel factor, which cancels it out.
+	untinted := func(cos float64) Color {
+		return White
+	}
+
+	if clearcoat := intensity(p.Clearcoat, rec); clearcoat > 0 {
+		if r.Rnd.Float64() < clearcoat*schlick(wo.Z, 1.5) {
+			coarse := intensity(p.ClearcoatRoughness, rec)
+			return reflectOff(newGGX(coarse, coarse), untinted)
+		}
+	}
+
+	if r.Rnd.Float64() < intensity(p.Metallic, rec) {
+		return reflectOff(dist, func(cos float64) Color {
+			return schlickColor(base, cos)
+		})
+	}
+
+	if r.Rnd.Float64() < schlick(wo.Z, specularIndex(intensity(p.Specular, rec))) {
+		return reflectOff(dist, untinted)
+	}
+
+	if r.Rnd.Float64() < transmission {
+		wm := dist.sampleNormal(wo, r.Rnd)
+		wi, ok := refract(wo, wm, p.RefIndex)
+		if !ok || wi.Z >= 0 {
+			return false, &Color{}, &geometry.Ray{}
+		}
+		attenuation := base.Scale(dist.weight(wo, wi))
+		scattered := scatterLocal(r, rec, basis, wi)
+		scattered.Spread = !dist.smooth()
+		return true, &attenuation, scattered
+	}
+
+	wi := geometry.RandCosineHemisphere(r.Rnd)
+	attenuation := p.diffuse(rec, base, wo, wi)
+	return true, &attenuation, spread(scatterLocal(r, rec, basis, wi))
+}
+
+// Evaluate returns the light reflected or refracted from wi by all the lobes of the material, each weighted by
+// the chance of Scatter picking it. Light is not gathered by lobes smooth enough to be mirrors or panes of glass.
+func (p Principled) Evaluate(r *geometry.Ray, rec *HitRecord, wi geometry.Unit) (Color, float64) {
+	basis, wo, back := localFrame(r, rec)
+	local := basis.Local(wi.Vec)
+	roughness := intensity(p.Roughness, rec)
+	dist := newGGX(roughness, roughness)
+	transmission := intensity(p.Transmission, rec)
+	if back && transmission > 0 {
+		value, pdf := evaluateDielectric(wo, local, dist, 1/p.RefIndex)
+		return White.Scale(value), pdf
+	}
+	if back || wo.Z <= 0 {
+		return Black, 0
+	}
+	base := White
+	if p.BaseColor != nil {
+		base = p.BaseColor.At(rec.u, rec.v, rec.p)
+	}
+
+	// remaining is the chance of Scatter reaching the next lobe.
+	value, pdf, remaining := Black, 0.0, 1.0
+	add := func(chance float64, lobeValue Color, lobePDF float64) {
+		value = value.Add(lobeValue.Scale(remaining * chance))
+		pdf += remaining * chance * lobePDF
+		remaining *= 1 - chance
+	}
+	// reflectOff returns the light reflected by microfacets from dist, tinted by the Fresnel factor of the lobe.
+	reflectOff := func(dist ggx, fresnel func(cos float64) Color) (Color, float64) {
+		wm, v, density, ok := dist.reflection(wo, local)
+		if !ok {
+			return Black, 0
+		}
+		return fresnel(wo.Dot(wm)).Scale(v), density
+	}
+	untinted := func(cos float64) Color {
+		return White
+	}
+
+	if clearcoat := intensity(p.Clearcoat, rec); clearcoat > 0 {
+		coarse := intensity(p.ClearcoatRoughness, rec)
+		v, density := reflectOff(newGGX(coarse, coarse), untinted)
+		add(clearcoat*schlick(wo.Z, 1.5), v, density)
+	}
+	v, density := reflectOff(dist, func(cos float64) Color {
+		return schlickColor(base, cos)
+	})
+	add(intensity(p.Metallic, rec), v, density)
+	v, density = reflectOff(dist, untinted)
+	add(schlick(wo.Z, specularIndex(intensity(p.Specular, rec))), v, density)
+	v, density = Black, 0
+	if _, refracted, refractedPDF, ok := dist.refraction(wo, local, p.RefIndex); ok {
+		v, density = base.Scale(refracted), refractedPDF
+	}
+	add(transmission, v, density)
+	if local.Z > 0 {
+		add(1, p.diffuse(rec, base, wo, local).Scale(local.Z/math.Pi), local.Z/math.Pi)
+	}
+	return value, pdf
+}
+
+// diffuse returns the attenuation of the diffuse base from wi towards wo, tinted towards white at grazing
+// angles by the sheen.
+func (p Principled) diffuse(rec *HitRecord, base Color, wo geometry.Vec, wi geometry.Vec) Color {
+	sheen := intensity(p.Sheen, rec)
+	if sheen <= 0 {
+		return base
+	}
+	half := wo.Add(wi).ToUnit()
+	grazing := sheen * math.Pow(1-clamp(half.Vec.Dot(wi), 0, 1), 5)
+	return base.Scale(1 - grazing).Add(White.Scale(grazing))
+}
+
+// specularIndex returns the index of refraction whose reflectance at normal incidence matches the
+// specular parameter of a Principled material, which maps 0 to 1 onto a reflectance of 0 to 8%.
+func specularIndex(specular float64) float64 {
+	f0 := math.Sqrt(0.08 * clamp(specular, 0, 1))
+	return (1 + f0) / math.Max(1-f0, 1e-6)
+}
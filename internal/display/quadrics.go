@@ -0,0 +1,491 @@
+package display
+
+import (
+	"math"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+// The shapes in this file are built around the Y axis, starting at their Center and extending upwards.
+// They can be oriented and placed in the world with RotateY and Translate like any other HitBoxer.
+//
+// Each shape can be swept partially around the Y axis. The sweep is measured the same way as the
+// longitude used by Sphere.UV, so that a texture wrapped around a Sphere lines up with the same
+// texture wrapped around a Cylinder or Cone.
+
+// sweep returns the angle in radians, within [0, 2π), swept around the Y axis to reach the point (x, z).
+func sweep(x float64, z float64) float64 {
+	return math.Pi - math.Atan2(z, x)
+}
+
+// radians converts a sweep in degrees to radians, clamped to a full turn.
+func radians(degrees float64) float64 {
+	return math.Min(degrees, 360) * math.Pi / 180
+}
+
+// hitDisk finds the intersection between a ray given in a shape's local frame and an annulus of the given
+// radii lying in the plane y = height. The normal of the annulus points up when up is true and down otherwise.
+func hitDisk(o geometry.Vec, d geometry.Unit, height float64, inner float64, outer float64, maxSweep float64, up bool, tMin float64, tMax float64) *HitRecord {
+	if d.Y == 0 {
+		return nil
+	}
+	t := (height - o.Y) / d.Y
+	if t <= tMin || t >= tMax {
+		return nil
+	}
+	p := o.Add(d.Scale(t))
+	dist2 := p.X*p.X + p.Z*p.Z
+	if dist2 > outer*outer || dist2 < inner*inner {
+		return nil
+	}
+	psi := sweep(p.X, p.Z)
+	if psi > maxSweep {
+		return nil
+	}
+	rec := &HitRecord{
+		t:      t,
+		p:      p,
+		normal: geometry.NewUnit(0, 1, 0),
+		u:      psi / maxSweep,
+		v:      (outer - math.Sqrt(dist2)) / (outer - inner),
+	}
+	revolve(rec, maxSweep, outer-inner)
+	if !up {
+		rec.flip()
+	}
+	return rec
+}
+
+// revolve sets the tangents of a HitRecord in the local frame of a surface swept around the Y axis,
+// where u follows the sweep and v runs along the profile of the surface over a length of about span.
+func revolve(rec *HitRecord, maxSweep float64, span float64) {
+	rec.dpdu = geometry.NewVec(rec.p.Z, 0, -rec.p.X).Scale(maxSweep)
+	profile := rec.normal.Cross(rec.dpdu)
+	if profile.LenSquared() > 0 {
+		rec.dpdv = profile.ToUnit().Scale(span)
+	}
+}
+
+// place moves a HitRecord computed in a shape's local frame into the world and assigns the shape's material.
+func place(rec *HitRecord, center geometry.Vec, material Material) *HitRecord {
+	rec.p = rec.p.Add(center)
+	rec.Material = material
+	return rec
+}
+
+// Disk represents a flat disk, or an annulus when Inner is non-zero, facing up the Y axis.
+type Disk struct {
+	Center   geometry.Vec
+	Radius   float64
+	Inner    float64
+	Sweep    float64 // degrees swept around the Y axis, 360 for a full disk
+	Material Material
+}
+
+// NewDisk returns a new Disk.
+func NewDisk(center geometry.Vec, radius float64, material Material) *Disk {
+	return &Disk{Center: center, Radius: radius, Sweep: 360, Material: material}
+}
+
+// Hit finds the intersection between a ray and the disk's surface.
+func (d *Disk) Hit(r *geometry.Ray, tMin float64, tMax float64) (bool, *HitRecord) {
+	o := r.Origin.Sub(d.Center)
+	rec := hitDisk(o, r.Direction, 0, d.Inner, d.Radius, radians(d.Sweep), true, tMin, tMax)
+	if rec == nil {
+		return false, nil
+	}
+	return true, place(rec, d.Center, d.Material)
+}
+
+// Box returns the bounding box of the Disk.
+func (d *Disk) Box(t0 float64, t1 float64) *AABB {
+	return NewAABB(
+		d.Center.Sub(geometry.NewVec(d.Radius, bias, d.Radius)),
+		d.Center.Add(geometry.NewVec(d.Radius, bias, d.Radius)),
+	)
+}
+
+// Cylinder represents a cylinder of a given Radius and Height standing on its Center.
+type Cylinder struct {
+	Center   geometry.Vec
+	Radius   float64
+	Height   float64
+	Sweep    float64 // degrees swept around the Y axis, 360 for a full cylinder
+	Capped   bool    // whether the ends are closed with disks
+	Material Material
+}
+
+// NewCylinder returns a new closed Cylinder.
+func NewCylinder(center geometry.Vec, radius float64, height float64, material Material) *Cylinder {
+	return &Cylinder{Center: center, Radius: radius, Height: height, Sweep: 360, Capped: true, Material: material}
+}
+
+// Hit finds the first intersection between a ray and the cylinder's surface.
+func (c *Cylinder) Hit(r *geometry.Ray, tMin float64, tMax float64) (bool, *HitRecord) {
+	o := r.Origin.Sub(c.Center)
+	d := r.Direction
+	maxSweep := radians(c.Sweep)
+
+	var rec *HitRecord
+	closest := tMax
+	a := d.X*d.X + d.Z*d.Z
+	b := 2 * (o.X*d.X + o.Z*d.Z)
+	cc := o.X*o.X + o.Z*o.Z - c.Radius*c.Radius
+	for _, t := range geometry.SolveQuadratic(a, b, cc) {
+		if t <= tMin || t >= closest {
+			continue
+		}
+		p := o.Add(d.Scale(t))
+		if p.Y < 0 || p.Y > c.Height {
+			continue
+		}
+		psi := sweep(p.X, p.Z)
+		if psi > maxSweep {
+			continue
+		}
+		rec = &HitRecord{
+			t:      t,
+			p:      p,
+			normal: geometry.NewUnit(p.X/c.Radius, 0, p.Z/c.Radius),
+			u:      psi / maxSweep,
+			v:      p.Y / c.Height,
+		}
+		revolve(rec, maxSweep, c.Height)
+		closest = t
+		break
+	}
+	if c.Capped {
+		if capRec := hitDisk(o, d, 0, 0, c.Radius, maxSweep, false, tMin, closest); capRec != nil {
+			rec, closest = capRec, capRec.t
+		}
+		if capRec := hitDisk(o, d, c.Height, 0, c.Radius, maxSweep, true, tMin, closest); capRec != nil {
+			rec = capRec
+		}
+	}
+	if rec == nil {
+		return false, nil
+	}
+	return true, place(rec, c.Center, c.Material)
+}
+
+// Box returns the bounding box of the Cylinder.
+func (c *Cylinder) Box(t0 float64, t1 float64) *AABB {
+	return NewAABB(
+		c.Center.Sub(geometry.NewVec(c.Radius, 0, c.Radius)),
+		c.Center.Add(geometry.NewVec(c.Radius, c.Height, c.Radius)),
+	)
+}
+
+// Cone represents a cone with a base of a given Radius centered on Center and its apex Height above it.
+type Cone struct {
+	Center   geometry.Vec
+	Radius   float64
+	Height   float64
+	Sweep    float64 // degrees swept around the Y axis, 360 for a full cone
+	Capped   bool    // whether the base is closed with a disk
+	Material Material
+}
+
+// NewCone returns a new closed Cone.
+func NewCone(center geometry.Vec, radius float64, height float64, material Material) *Cone {
+	return &Cone{Center: center, Radius: radius, Height: height, Sweep: 360, Capped: true, Material: material}
+}
+
+// Hit finds the first intersection between a ray and the cone's surface.
+func (c *Cone) Hit(r *geometry.Ray, tMin float64, tMax float64) (bool, *HitRecord) {
+	o := r.Origin.Sub(c.Center)
+	d := r.Direction
+	maxSweep := radians(c.Sweep)
+
+	// The cone satisfies x^2 + z^2 = k * (h - y)^2.
+	k := (c.Radius / c.Height) * (c.Radius / c.Height)
+	h := c.Height - o.Y
+
+	var rec *HitRecord
+	closest := tMax
+	a := d.X*d.X + d.Z*d.Z - k*d.Y*d.Y
+	b := 2 * (o.X*d.X + o.Z*d.Z + k*h*d.Y)
+	cc := o.X*o.X + o.Z*o.Z - k*h*h
+	for _, t := range geometry.SolveQuadratic(a, b, cc) {
+		if t <= tMin || t >= closest {
+			continue
+		}
+		p := o.Add(d.Scale(t))
+		if p.Y < 0 || p.Y > c.Height {
+			continue
+		}
+		psi := sweep(p.X, p.Z)
+		if psi > maxSweep {
+			continue
+		}
+		rec = &HitRecord{
+			t:      t,
+			p:      p,
+			normal: geometry.NewVec(p.X, k*(c.Height-p.Y), p.Z).ToUnit(),
+			u:      psi / maxSweep,
+			v:      p.Y / c.Height,
+		}
+		revolve(rec, maxSweep, c.Height)
+		closest = t
+		break
+	}
+	if c.Capped {
+		if capRec := hitDisk(o, d, 0, 0, c.Radius, maxSweep, false, tMin, closest); capRec != nil {
+			rec = capRec
+		}
+	}
+	if rec == nil {
+		return false, nil
+	}
+	return true, place(rec, c.Center, c.Material)
+}
+
+// Box returns the bounding box of the Cone.
+func (c *Cone) Box(t0 float64, t1 float64) *AABB {
+	return NewAABB(
+		c.Center.Sub(geometry.NewVec(c.Radius, 0, c.Radius)),
+		c.Center.Add(geometry.NewVec(c.Radius, c.Height, c.Radius)),
+	)
+}
+
+// Paraboloid represents a bowl with its vertex on Center, opening up to a rim of a given Radius at Height.
+type Paraboloid struct {
+	Center   geometry.Vec
+	Radius   float64
+	Height   float64
+	Sweep    float64 // degrees swept around the Y axis, 360 for a full paraboloid
+	Capped   bool    // whether the rim is closed with a disk
+	Material Material
+}
+
+// NewParaboloid returns a new closed Paraboloid.
+func NewParaboloid(center geometry.Vec, radius float64, height float64, material Material) *Paraboloid {
+	return &Paraboloid{Center: center, Radius: radius, Height: height, Sweep: 360, Capped: true, Material: material}
+}
+
+// Hit finds the first intersection between a ray and the paraboloid's surface.
+func (pb *Paraboloid) Hit(r *geometry.Ray, tMin float64, tMax float64) (bool, *HitRecord) {
+	o := r.Origin.Sub(pb.Center)
+	d := r.Direction
+	maxSweep := radians(pb.Sweep)
+
+	// The paraboloid satisfies y = k * (x^2 + z^2).
+	k := pb.Height / (pb.Radius * pb.Radius)
+
+	var rec *HitRecord
+	closest := tMax
+	a := k * (d.X*d.X + d.Z*d.Z)
+	b := 2*k*(o.X*d.X+o.Z*d.Z) - d.Y
+	cc := k*(o.X*o.X+o.Z*o.Z) - o.Y
+	for _, t := range geometry.SolveQuadratic(a, b, cc) {
+		if t <= tMin || t >= closest {
+			continue
+		}
+		p := o.Add(d.Scale(t))
+		if p.Y < 0 || p.Y > pb.Height {
+			continue
+		}
+		psi := sweep(p.X, p.Z)
+		if psi > maxSweep {
+			continue
+		}
+		rec = &HitRecord{
+			t:      t,
+			p:      p,
+			normal: geometry.NewVec(2*k*p.X, -1, 2*k*p.Z).ToUnit(),
+			u:      psi / maxSweep,
+			v:      p.Y / pb.Height,
+		}
+		revolve(rec, maxSweep, pb.Height)
+		closest = t
+		break
+	}
+	if pb.Capped {
+		if capRec := hitDisk(o, d, pb.Height, 0, pb.Radius, maxSweep, true, tMin, closest); capRec != nil {
+			rec = capRec
+		}
+	}
+	if rec == nil {
+		return false, nil
+	}
+	return true, place(rec, pb.Center, pb.Material)
+}
+
+// Box returns the bounding box of the Paraboloid.
+func (pb *Paraboloid) Box(t0 float64, t1 float64) *AABB {
+	return NewAABB(
+		pb.Center.Sub(geometry.NewVec(pb.Radius, 0, pb.Radius)),
+		pb.Center.Add(geometry.NewVec(pb.Radius, pb.Height, pb.Radius)),
+	)
+}
+
+// Hyperboloid represents a hyperboloid of one sheet standing on its Center.
+// Both ends have the given Radius and the surface narrows to Waist halfway up its Height.
+type Hyperboloid struct {
+	Center   geometry.Vec
+	Radius   float64
+	Waist    float64
+	Height   float64
+	Sweep    float64 // degrees swept around the Y axis, 360 for a full hyperboloid
+	Capped   bool    // whether the ends are closed with disks
+	Material Material
+}
+
+// NewHyperboloid returns a new closed Hyperboloid.
+func NewHyperboloid(center geometry.Vec, radius float64, waist float64, height float64, material Material) *Hyperboloid {
+	return &Hyperboloid{Center: center, Radius: radius, Waist: waist, Height: height, Sweep: 360, Capped: true, Material: material}
+}
+
+// Hit finds the first intersection between a ray and the hyperboloid's surface.
+func (hb *Hyperboloid) Hit(r *geometry.Ray, tMin float64, tMax float64) (bool, *HitRecord) {
+	o := r.Origin.Sub(hb.Center)
+	d := r.Direction
+	maxSweep := radians(hb.Sweep)
+
+	// The hyperboloid satisfies x^2 + z^2 - k * (y - m)^2 = w^2, with m the height of the waist.
+	m := hb.Height / 2
+	k := (hb.Radius*hb.Radius - hb.Waist*hb.Waist) / (m * m)
+	oy := o.Y - m
+
+	var rec *HitRecord
+	closest := tMax
+	a := d.X*d.X + d.Z*d.Z - k*d.Y*d.Y
+	b := 2 * (o.X*d.X + o.Z*d.Z - k*oy*d.Y)
+	cc := o.X*o.X + o.Z*o.Z - k*oy*oy - hb.Waist*hb.Waist
+	for _, t := range geometry.SolveQuadratic(a, b, cc) {
+		if t <= tMin || t >= closest {
+			continue
+		}
+		p := o.Add(d.Scale(t))
+		if p.Y < 0 || p.Y > hb.Height {
+			continue
+		}
+		psi := sweep(p.X, p.Z)
+		if psi > maxSweep {
+			continue
+		}
+		rec = &HitRecord{
+			t:      t,
+			p:      p,
+			normal: geometry.NewVec(p.X, -k*(p.Y-m), p.Z).ToUnit(),
+			u:      psi / maxSweep,
+			v:      p.Y / hb.Height,
+		}
+		revolve(rec, maxSweep, hb.Height)
+		closest = t
+		break
+	}
+	if hb.Capped {
+		if capRec := hitDisk(o, d, 0, 0, hb.Radius, maxSweep, false, tMin, closest); capRec != nil {
+			rec, closest = capRec, capRec.t
+		}
+		if capRec := hitDisk(o, d, hb.Height, 0, hb.Radius, maxSweep, true, tMin, closest); capRec != nil {
+			rec = capRec
+		}
+	}
+	if rec == nil {
+		return false, nil
+	}
+	return true, place(rec, hb.Center, hb.Material)
+}
+
+// Box returns the bounding box of the Hyperboloid.
+func (hb *Hyperboloid) Box(t0 float64, t1 float64) *AABB {
+	return NewAABB(
+		hb.Center.Sub(geometry.NewVec(hb.Radius, 0, hb.Radius)),
+		hb.Center.Add(geometry.NewVec(hb.Radius, hb.Height, hb.Radius)),
+	)
+}
+
+// Torus represents a ring lying flat around its Center.
+// Major is the distance from the Center to the middle of the tube and Minor is the radius of the tube.
+type Torus struct {
+	Center   geometry.Vec
+	Major    float64
+	Minor    float64
+	Sweep    float64 // degrees swept around the Y axis, 360 for a full torus
+	Material Material
+}
+
+// NewTorus returns a new Torus.
+func NewTorus(center geometry.Vec, major float64, minor float64, material Material) *Torus {
+	return &Torus{Center: center, Major: major, Minor: minor, Sweep: 360, Material: material}
+}
+
+// Hit finds the first intersection between a ray and the torus' surface.
+func (tr *Torus) Hit(r *geometry.Ray, tMin float64, tMax float64) (bool, *HitRecord) {
+	d := r.Direction
+	maxSweep := radians(tr.Sweep)
+
+	// Move the origin of the ray to the point nearest to the center of the torus
+	// to keep the coefficients of the quartic well conditioned for distant rays.
+	o := r.Origin.Sub(tr.Center)
+	shift := -o.Dot(d.Vec) / d.Dot(d)
+	o = o.Add(d.Scale(shift))
+
+	// The torus satisfies (x^2 + y^2 + z^2 + R^2 - r^2)^2 = 4 * R^2 * (x^2 + z^2).
+	major2 := tr.Major * tr.Major
+	a := d.Dot(d)
+	b := 2 * o.Dot(d.Vec)
+	c := o.Dot(o) + major2 - tr.Minor*tr.Minor
+	roots := geometry.SolveQuartic(
+		a*a,
+		2*a*b,
+		b*b+2*a*c-4*major2*(d.X*d.X+d.Z*d.Z),
+		2*b*c-8*major2*(o.X*d.X+o.Z*d.Z),
+		c*c-4*major2*(o.X*o.X+o.Z*o.Z),
+	)
+	for _, s := range roots {
+		s = tr.polish(o, d, s)
+		t := s + shift
+		if t <= tMin || t >= tMax {
+			continue
+		}
+		p := o.Add(d.Scale(s))
+		psi := sweep(p.X, p.Z)
+		if psi > maxSweep {
+			continue
+		}
+		// The normal points away from the closest point on the circle running through the middle of the tube.
+		ring := math.Sqrt(p.X*p.X + p.Z*p.Z)
+		tube := geometry.NewVec(p.X, 0, p.Z).Scale(tr.Major / ring)
+		theta := math.Atan2(p.Y, ring-tr.Major)
+		rec := &HitRecord{
+			t:      t,
+			p:      p,
+			normal: p.Sub(tube).ToUnit(),
+			u:      psi / maxSweep,
+			v:      (theta + math.Pi) / (2 * math.Pi),
+		}
+		revolve(rec, maxSweep, 2*math.Pi*tr.Minor)
+		return true, place(rec, tr.Center, tr.Material)
+	}
+	return false, nil
+}
+
+// polish refines a root of the torus equation with a few iterations of Newton's method,
+// since the closed-form quartic solution loses precision for grazing rays.
+func (tr *Torus) polish(o geometry.Vec, d geometry.Unit, s float64) float64 {
+	major2 := tr.Major * tr.Major
+	for i := 0; i < 2; i++ {
+		p := o.Add(d.Scale(s))
+		sum := p.Dot(p) + major2 - tr.Minor*tr.Minor
+		f := sum*sum - 4*major2*(p.X*p.X+p.Z*p.Z)
+		grad := p.Scale(4 * sum).Sub(geometry.NewVec(p.X, 0, p.Z).Scale(8 * major2))
+		df := grad.Dot(d.Vec)
+		if df == 0 {
+			break
+		}
+		s -= f / df
+	}
+	return s
+}
+
+// Box returns the bounding box of the Torus.
+func (tr *Torus) Box(t0 float64, t1 float64) *AABB {
+	outer := tr.Major + tr.Minor
+	return NewAABB(
+		tr.Center.Sub(geometry.NewVec(outer, tr.Minor, outer)),
+		tr.Center.Add(geometry.NewVec(outer, tr.Minor, outer)),
+	)
+}
@@ -49,12 +49,14 @@ func (r *RotateY) left(dir geometry.Vec) geometry.Vec {
 	return geometry.NewVec(x, dir.Y, z)
 }
 
-// Hit calculates if the ray hits the HitBoxer. If so, the normal and point of the HitRecord are rotated.
+// Hit calculates if the ray hits the HitBoxer. If so, the normal, tangents and point of the HitRecord are rotated.
 func (r *RotateY) Hit(ray *geometry.Ray, tMin float64, tMax float64) (bool, *HitRecord) {
 	ray2 := geometry.NewRay(r.left(ray.Origin), geometry.Unit{Vec: r.left(ray.Direction.Vec)}, ray.Time, ray.Rnd)
 	didHit, record := r.Child.Hit(ray2, tMin, tMax)
 	if didHit {
 		record.normal = geometry.Unit{Vec: r.right(record.normal.Vec)}
+		record.dpdu = r.right(record.dpdu)
+		record.dpdv = r.right(record.dpdv)
 		record.p = r.right(record.p)
 	}
 	return didHit, record
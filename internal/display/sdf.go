@@ -0,0 +1,360 @@
+package display
+
+import (
+	"math"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+// DistanceField represents a signed distance function.
+// Distance is negative inside the solid, positive outside, and never larger than the distance to its surface.
+type DistanceField interface {
+	Distance(p geometry.Vec) float64
+}
+
+// SDF represents a solid described by a DistanceField, rendered by sphere tracing.
+//
+// Since a DistanceField can extend infinitely, the solid is only searched for within Bounds,
+// which is also the box used to place the SDF in a BVH.
+type SDF struct {
+	Field    DistanceField
+	Bounds   *AABB
+	Material Material
+	MaxSteps int     // the maximum number of steps taken along a ray before giving up
+	Epsilon  float64 // the distance to the surface at which a ray is considered to hit it
+}
+
+// NewSDF returns a new SDF.
+func NewSDF(field DistanceField, bounds *AABB, material Material) *SDF {
+	return &SDF{Field: field, Bounds: bounds, Material: material, MaxSteps: 256, Epsilon: 0.0001}
+}
+
+// Hit finds the first intersection between a ray and the surface of the distance field.
+//
+// The ray is marched forward by the distance to the closest surface until it comes within Epsilon of it.
+// Rays starting inside the solid march on the negated distance so that they find the way out.
+func (s *SDF) Hit(r *geometry.Ray, tMin float64, tMax float64) (bool, *HitRecord) {
+	hit, t, tEnd := s.Bounds.Clip(r, tMin, tMax)
+	if !hit {
+		return false, nil
+	}
+	speed := r.Direction.Len()
+
+	sign := 1.0
+	d := s.Field.Distance(r.At(t))
+	if d < 0 {
+		sign = -1
+	}
+	// A ray leaving the surface, such as one scattered from it, starts within Epsilon of the surface and
+	// has to get clear of it before a hit is accepted. The normal tells which side it is heading to.
+	escaped := math.Abs(d) >= s.Epsilon
+	if !escaped {
+		if s.normal(r.At(t)).Dot(r.Direction) < 0 {
+			sign = -1
+		} else {
+			sign = 1
+		}
+	}
+
+	for i := 0; i < s.MaxSteps && t < tEnd; i++ {
+		d = sign * s.Field.Distance(r.At(t))
+		if escaped && d < s.Epsilon {
+			p := r.At(t)
+			u, v := s.UV(p)
+			return true, &HitRecord{
+				t:        t,
+				p:        p,
+				normal:   s.normal(p),
+				Material: s.Material,
+				u:        u,
+				v:        v,
+			}
+		}
+		if d >= s.Epsilon {
+			escaped = true
+		}
+		t += math.Max(d, s.Epsilon) / speed
+	}
+	return false, nil
+}
+
+// normal estimates the gradient of the distance field at p with central differences
+// taken along the four corners of a tetrahedron.
+func (s *SDF) normal(p geometry.Vec) geometry.Unit {
+	h := s.Epsilon
+	n := geometry.Vec{}
+	for _, k := range []geometry.Vec{{X: 1, Y: -1, Z: -1}, {X: -1, Y: -1, Z: 1}, {X: -1, Y: 1, Z: -1}, {X: 1, Y: 1, Z: 1}} {
+		n = n.Add(k.Scale(s.Field.Distance(p.Add(k.Scale(h)))))
+	}
+	if n.Zero() {
+		return geometry.NewUnit(0, 1, 0)
+	}
+	return n.ToUnit()
+}
+
+// UV projects the point p onto a sphere around the center of the bounds, the same way as Sphere.UV.
+func (s *SDF) UV(p geometry.Vec) (float64, float64) {
+	center := s.Bounds.Min.Add(s.Bounds.Max).Scale(0.5)
+	p2 := p.Sub(center)
+	if p2.Zero() {
+		return 0, 0
+	}
+	p2 = p2.ToUnit().Vec
+	phi := math.Atan2(p2.Z, p2.X)
+	theta := math.Asin(p2.Y)
+	u := 1 - (phi+math.Pi)/(2*math.Pi)
+	v := (theta + math.Pi/2) / math.Pi
+	return u, v
+}
+
+// Box returns the bounds of the SDF.
+func (s *SDF) Box(t0 float64, t1 float64) *AABB {
+	return s.Bounds
+}
+
+// SphereField is the DistanceField of a sphere.
+type SphereField struct {
+	Center geometry.Vec
+	Radius float64
+}
+
+// NewSphereField returns a new SphereField.
+func NewSphereField(center geometry.Vec, radius float64) SphereField {
+	return SphereField{Center: center, Radius: radius}
+}
+
+// Distance returns the signed distance from p to the sphere.
+func (s SphereField) Distance(p geometry.Vec) float64 {
+	return p.Sub(s.Center).Len() - s.Radius
+}
+
+// BoxField is the DistanceField of a box, given by its Center and its half extents Size.
+type BoxField struct {
+	Center geometry.Vec
+	Size   geometry.Vec
+}
+
+// NewBoxField returns a new BoxField.
+func NewBoxField(center geometry.Vec, size geometry.Vec) BoxField {
+	return BoxField{Center: center, Size: size}
+}
+
+// Distance returns the signed distance from p to the box.
+func (b BoxField) Distance(p geometry.Vec) float64 {
+	return boxDistance(p.Sub(b.Center), b.Size)
+}
+
+// boxDistance returns the signed distance from p to a box centered on the origin with the given half extents.
+func boxDistance(p geometry.Vec, size geometry.Vec) float64 {
+	q := abs(p).Sub(size)
+	outside := q.Max(geometry.Vec{}).Len()
+	inside := math.Min(math.Max(q.X, math.Max(q.Y, q.Z)), 0)
+	return outside + inside
+}
+
+// RoundBoxField is the DistanceField of a box with its edges rounded by Radius.
+type RoundBoxField struct {
+	Center geometry.Vec
+	Size   geometry.Vec
+	Radius float64
+}
+
+// NewRoundBoxField returns a new RoundBoxField.
+func NewRoundBoxField(center geometry.Vec, size geometry.Vec, radius float64) RoundBoxField {
+	return RoundBoxField{Center: center, Size: size, Radius: radius}
+}
+
+// Distance returns the signed distance from p to the rounded box.
+func (b RoundBoxField) Distance(p geometry.Vec) float64 {
+	inner := b.Size.Sub(geometry.NewVec(b.Radius, b.Radius, b.Radius))
+	return boxDistance(p.Sub(b.Center), inner) - b.Radius
+}
+
+// TorusField is the DistanceField of a torus lying flat around its Center.
+type TorusField struct {
+	Center geometry.Vec
+	Major  float64
+	Minor  float64
+}
+
+// NewTorusField returns a new TorusField.
+func NewTorusField(center geometry.Vec, major float64, minor float64) TorusField {
+	return TorusField{Center: center, Major: major, Minor: minor}
+}
+
+// Distance returns the signed distance from p to the torus.
+func (tf TorusField) Distance(p geometry.Vec) float64 {
+	p = p.Sub(tf.Center)
+	ring := math.Hypot(p.X, p.Z) - tf.Major
+	return math.Hypot(ring, p.Y) - tf.Minor
+}
+
+// CapsuleField is the DistanceField of a capsule, a cylinder of a given Radius from A to B with rounded ends.
+type CapsuleField struct {
+	A      geometry.Vec
+	B      geometry.Vec
+	Radius float64
+}
+
+// NewCapsuleField returns a new CapsuleField.
+func NewCapsuleField(a geometry.Vec, b geometry.Vec, radius float64) CapsuleField {
+	return CapsuleField{A: a, B: b, Radius: radius}
+}
+
+// Distance returns the signed distance from p to the capsule.
+func (c CapsuleField) Distance(p geometry.Vec) float64 {
+	pa := p.Sub(c.A)
+	ba := c.B.Sub(c.A)
+	h := clamp(pa.Dot(ba)/ba.Dot(ba), 0, 1)
+	return pa.Sub(ba.Scale(h)).Len() - c.Radius
+}
+
+// SmoothUnion blends two DistanceFields together, rounding the seam between them over a distance K.
+type SmoothUnion struct {
+	A DistanceField
+	B DistanceField
+	K float64
+}
+
+// NewSmoothUnion returns a new SmoothUnion.
+func NewSmoothUnion(a DistanceField, b DistanceField, k float64) SmoothUnion {
+	return SmoothUnion{A: a, B: b, K: k}
+}
+
+// Distance returns the signed distance from p to the blend of both fields.
+func (su SmoothUnion) Distance(p geometry.Vec) float64 {
+	d1 := su.A.Distance(p)
+	d2 := su.B.Distance(p)
+	if su.K <= 0 {
+		return math.Min(d1, d2)
+	}
+	h := clamp(0.5+0.5*(d2-d1)/su.K, 0, 1)
+	return lerp(d2, d1, h) - su.K*h*(1-h)
+}
+
+// SmoothSubtraction carves the DistanceField B out of A, rounding the edges of the cut over a distance K.
+type SmoothSubtraction struct {
+	A DistanceField
+	B DistanceField
+	K float64
+}
+
+// NewSmoothSubtraction returns a new SmoothSubtraction.
+func NewSmoothSubtraction(a DistanceField, b DistanceField, k float64) SmoothSubtraction {
+	return SmoothSubtraction{A: a, B: b, K: k}
+}
+
+// Distance returns the signed distance from p to A with B carved out of it.
+func (ss SmoothSubtraction) Distance(p geometry.Vec) float64 {
+	d1 := ss.B.Distance(p)
+	d2 := ss.A.Distance(p)
+	if ss.K <= 0 {
+		return math.Max(d2, -d1)
+	}
+	h := clamp(0.5-0.5*(d2+d1)/ss.K, 0, 1)
+	return lerp(d2, -d1, h) + ss.K*h*(1-h)
+}
+
+// Repeat tiles a DistanceField infinitely, once every Period along each axis.
+// An axis with a Period of zero is not repeated.
+type Repeat struct {
+	Field  DistanceField
+	Period geometry.Vec
+}
+
+// NewRepeat returns a new Repeat.
+func NewRepeat(field DistanceField, period geometry.Vec) Repeat {
+	return Repeat{Field: field, Period: period}
+}
+
+// Distance returns the signed distance from p to the closest copy of the field.
+func (rp Repeat) Distance(p geometry.Vec) float64 {
+	wrap := func(x float64, period float64) float64 {
+		if period <= 0 {
+			return x
+		}
+		return x - period*math.Round(x/period)
+	}
+	return rp.Field.Distance(geometry.NewVec(wrap(p.X, rp.Period.X), wrap(p.Y, rp.Period.Y), wrap(p.Z, rp.Period.Z)))
+}
+
+// Twist twists a DistanceField around the Y axis by Rate radians per unit of height.
+type Twist struct {
+	Field DistanceField
+	Rate  float64
+}
+
+// NewTwist returns a new Twist.
+func NewTwist(field DistanceField, rate float64) Twist {
+	return Twist{Field: field, Rate: rate}
+}
+
+// Distance returns the signed distance from p to the twisted field.
+//
+// Twisting stretches space, so the distance of the underlying field is scaled down by how much
+// the twist stretches it at p to keep sphere tracing from stepping through the surface.
+func (tw Twist) Distance(p geometry.Vec) float64 {
+	angle := tw.Rate * p.Y
+	sin, cos := math.Sincos(angle)
+	q := geometry.NewVec(cos*p.X-sin*p.Z, p.Y, sin*p.X+cos*p.Z)
+	stretch := math.Sqrt(1 + tw.Rate*tw.Rate*(p.X*p.X+p.Z*p.Z))
+	return tw.Field.Distance(q) / stretch
+}
+
+// Mandelbulb is the distance estimate of the Mandelbulb fractal, scaled by Scale and centered on Center.
+type Mandelbulb struct {
+	Center     geometry.Vec
+	Scale      float64
+	Power      float64
+	Iterations int
+}
+
+// NewMandelbulb returns a new Mandelbulb of the classic power 8.
+func NewMandelbulb(center geometry.Vec, scale float64) Mandelbulb {
+	return Mandelbulb{Center: center, Scale: scale, Power: 8, Iterations: 12}
+}
+
+// Distance returns the estimated distance from p to the fractal's surface.
+func (m Mandelbulb) Distance(p geometry.Vec) float64 {
+	c := p.Sub(m.Center).Scale(1 / m.Scale)
+	z := c
+	dr := 1.0
+	r := 0.0
+	for i := 0; i < m.Iterations; i++ {
+		r = z.Len()
+		if r > 2 {
+			break
+		}
+		if r == 0 {
+			// The center, and any point iterating back to it, lies inside the set.
+			return 0
+		}
+		// Raise z to the given power in spherical coordinates.
+		theta := math.Acos(clamp(z.Y/r, -1, 1)) * m.Power
+		phi := math.Atan2(z.Z, z.X) * m.Power
+		dr = math.Pow(r, m.Power-1)*m.Power*dr + 1
+		zr := math.Pow(r, m.Power)
+		sinTheta, cosTheta := math.Sincos(theta)
+		sinPhi, cosPhi := math.Sincos(phi)
+		z = geometry.NewVec(sinTheta*cosPhi, cosTheta, sinTheta*sinPhi).Scale(zr).Add(c)
+	}
+	if r == 0 {
+		return -m.Scale
+	}
+	return 0.5 * math.Log(r) * r / dr * m.Scale
+}
+
+// abs returns the vector made of the absolute values of the elements of v.
+func abs(v geometry.Vec) geometry.Vec {
+	return geometry.NewVec(math.Abs(v.X), math.Abs(v.Y), math.Abs(v.Z))
+}
+
+// clamp restricts x to the range [min, max].
+func clamp(x float64, min float64, max float64) float64 {
+	return math.Max(min, math.Min(max, x))
+}
+
+// lerp linearly interpolates from a to b by t.
+func lerp(a float64, b float64, t float64) float64 {
+	return a + (b-a)*t
+}
@@ -59,6 +59,7 @@ func (s *Sphere) Hit(r *geometry.Ray, tMin float64, tMax float64) (bool, *HitRec
 	if t > tMin && t < tMax {
 		hitPoint := r.At(t)
 		u, v := s.UV(hitPoint, t)
+		dpdu, dpdv := sphereTangents(hitPoint.Sub(s.Center))
 		hr := HitRecord{
 			t:        t,
 			p:        hitPoint,
@@ -66,6 +67,8 @@ func (s *Sphere) Hit(r *geometry.Ray, tMin float64, tMax float64) (bool, *HitRec
 			Material: s.Material,
 			u:        u,
 			v:        v,
+			dpdu:     dpdu,
+			dpdv:     dpdv,
 		}
 		return true, &hr
 	}
@@ -74,6 +77,7 @@ func (s *Sphere) Hit(r *geometry.Ray, tMin float64, tMax float64) (bool, *HitRec
 	if t > tMin && t < tMax {
 		hitPoint := r.At(t)
 		u, v := s.UV(hitPoint, t)
+		dpdu, dpdv := sphereTangents(hitPoint.Sub(s.Center))
 		hr := HitRecord{
 			t:        t,
 			p:        hitPoint,
@@ -81,6 +85,8 @@ func (s *Sphere) Hit(r *geometry.Ray, tMin float64, tMax float64) (bool, *HitRec
 			Material: s.Material,
 			u:        u,
 			v:        v,
+			dpdu:     dpdu,
+			dpdv:     dpdv,
 		}
 		return true, &hr
 	}
@@ -103,6 +109,7 @@ func (s *MovingSphere) Hit(r *geometry.Ray, dMin float64, dMax float64) (bool, *
 	if d > dMin && d < dMax {
 		hitPoint := r.At(d)
 		u, v := s.UV(hitPoint, d)
+		dpdu, dpdv := sphereTangents(hitPoint.Sub(s.Center(r.Time)))
 		hr := HitRecord{
 			t:        d,
 			p:        hitPoint,
@@ -110,6 +117,8 @@ func (s *MovingSphere) Hit(r *geometry.Ray, dMin float64, dMax float64) (bool, *
 			Material: s.Material,
 			u:        u,
 			v:        v,
+			dpdu:     dpdu,
+			dpdv:     dpdv,
 		}
 		return true, &hr
 	}
@@ -118,6 +127,7 @@ func (s *MovingSphere) Hit(r *geometry.Ray, dMin float64, dMax float64) (bool, *
 	if d > dMin && d < dMax {
 		hitPoint := r.At(d)
 		u, v := s.UV(hitPoint, d)
+		dpdu, dpdv := sphereTangents(hitPoint.Sub(s.Center(r.Time)))
 		hr := HitRecord{
 			t:        d,
 			p:        hitPoint,
@@ -125,6 +135,8 @@ func (s *MovingSphere) Hit(r *geometry.Ray, dMin float64, dMax float64) (bool, *
 			Material: s.Material,
 			u:        u,
 			v:        v,
+			dpdu:     dpdu,
+			dpdv:     dpdv,
 		}
 		return true, &hr
 	}
@@ -172,6 +184,18 @@ func (s *Sphere) UV(p geometry.Vec, t float64) (float64, float64) {
 	return u, v
 }
 
+// sphereTangents returns the derivatives along u and v of a point on a sphere, at the given offset from its center.
+func sphereTangents(offset geometry.Vec) (geometry.Vec, geometry.Vec) {
+	rho := math.Sqrt(offset.X*offset.X + offset.Z*offset.Z)
+	if rho < 1e-9 {
+		// The tangents are undefined at the poles.
+		return geometry.Vec{}, geometry.Vec{}
+	}
+	dpdu := geometry.NewVec(offset.Z, 0, -offset.X).Scale(2 * math.Pi)
+	dpdv := geometry.NewVec(-offset.Y*offset.X/rho, rho, -offset.Y*offset.Z/rho).Scale(math.Pi)
+	return dpdu, dpdv
+}
+
 func (s *MovingSphere) UV(p geometry.Vec, t float64) (float64, float64) {
 	p2 := p.Sub(s.Center(t)).Scale(1 / s.Radius)
 	phi := math.Atan2(p2.Z, p2.X)
@@ -237,6 +261,8 @@ func (rect *Rectangle) Hit(ray *geometry.Ray, dMin float64, dMax float64) (bool,
 			Material: rect.Material,
 			u:        (e1 - rect.Min.Y) / (rect.Max.Y - rect.Min.Y),
 			v:        (e2 - rect.Min.Z) / (rect.Max.Z - rect.Min.Z),
+			dpdu:     geometry.NewVec(0, rect.Max.Y-rect.Min.Y, 0),
+			dpdv:     geometry.NewVec(0, 0, rect.Max.Z-rect.Min.Z),
 		}
 		return true, &hr
 	case 1:
@@ -261,6 +287,8 @@ func (rect *Rectangle) Hit(ray *geometry.Ray, dMin float64, dMax float64) (bool,
 			Material: rect.Material,
 			u:        (e1 - rect.Min.Z) / (rect.Max.Z - rect.Min.Z),
 			v:        (e2 - rect.Min.X) / (rect.Max.X - rect.Min.X),
+			dpdu:     geometry.NewVec(0, 0, rect.Max.Z-rect.Min.Z),
+			dpdv:     geometry.NewVec(rect.Max.X-rect.Min.X, 0, 0),
 		}
 		return true, &hr
 	case 2:
@@ -285,6 +313,8 @@ func (rect *Rectangle) Hit(ray *geometry.Ray, dMin float64, dMax float64) (bool,
 			Material: rect.Material,
 			u:        (e1 - rect.Min.X) / (rect.Max.X - rect.Min.X),
 			v:        (e2 - rect.Min.Y) / (rect.Max.Y - rect.Min.Y),
+			dpdu:     geometry.NewVec(rect.Max.X-rect.Min.X, 0, 0),
+			dpdv:     geometry.NewVec(0, rect.Max.Y-rect.Min.Y, 0),
 		}
 		return true, &hr
 	default:
@@ -328,6 +358,9 @@ func NewBlock(min geometry.Vec, max geometry.Vec, material Material) *Block {
 }
 
 // Volume represents a HitBoxer filled with a material with a given density.
+//
+// The box must be convex and may not overlap other volumes. Media that fill any closed surface, overlap or
+// surround the camera are instead enclosed by a MediumBoundary, or fill the whole scene.
 type Volume struct {
 	box     HitBoxer
 	density float64
@@ -340,14 +373,29 @@ func NewVolume(box HitBoxer, density float64, phase Material) *Volume {
 }
 
 func (v *Volume) Hit(ray *geometry.Ray, dMin float64, dMax float64) (bool, *HitRecord) {
-	didHit1, hit1 := v.box.Hit(ray, -math.MaxFloat64, math.MaxFloat64)
-	if !didHit1 {
+	d0, d1, ok := span(v.box, ray, dMin, dMax)
+	if !ok {
 		return false, nil
 	}
-	didHit2, hit2 := v.box.Hit(ray, hit1.t+bias, math.MaxFloat64)
-	if !didHit2 {
+	dInside := d1 - d0
+	dHit := -(1 / v.density) * math.Log(ray.Rnd.Float64())
+	if dHit >= dInside {
 		return false, nil
 	}
+	return true, scatteringEvent(ray, d0+dHit, v.phase)
+}
+
+// span returns the distances along a ray at which it enters and leaves a closed box, clipped to the range
+// from dMin to dMax, or false when the ray does not pass through the box within the range.
+func span(box HitBoxer, ray *geometry.Ray, dMin float64, dMax float64) (float64, float64, bool) {
+	didHit1, hit1 := box.Hit(ray, -math.MaxFloat64, math.MaxFloat64)
+	if !didHit1 {
+		return 0, 0, false
+	}
+	didHit2, hit2 := box.Hit(ray, hit1.t+bias, math.MaxFloat64)
+	if !didHit2 {
+		return 0, 0, false
+	}
 	if hit1.t < dMin {
 		hit1.t = dMin
 	}
@@ -355,24 +403,24 @@ func (v *Volume) Hit(ray *geometry.Ray, dMin float64, dMax float64) (bool, *HitR
 		hit2.t = dMax
 	}
 	if hit1.t > hit2.t {
-		return false, nil
+		return 0, 0, false
 	}
 	if hit1.t < 0 {
 		hit1.t = 0
 	}
-	dInside := hit2.t - hit1.t
-	dHit := -(1 / v.density) * math.Log(ray.Rnd.Float64())
-	if dHit >= dInside {
-		return false, nil
-	}
-	d := hit1.t + dHit
-	return true, &HitRecord{
+	return hit1.t, hit2.t, true
+}
+
+// scatteringEvent returns the HitRecord of a ray scattering inside a volume at the distance d, with the
+// given phase material.
+func scatteringEvent(ray *geometry.Ray, d float64, phase Material) *HitRecord {
+	return &HitRecord{
 		t:        d,
 		normal:   geometry.NewUnit(1, 0, 0),
 		u:        0,
 		v:        0,
 		p:        ray.At(d),
-		Material: v.phase,
+		Material: phase,
 	}
 }
 
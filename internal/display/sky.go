@@ -0,0 +1,234 @@
+package display
+
+import (
+	"math"
+	"time"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+const (
+	// sunRadius is the angular radius of the sun seen from the earth, in radians.
+	sunRadius = 0.00465
+	// sunIlluminance is the light of the sun falling on a surface facing it above the atmosphere, in the
+	// thousands of lux the sky is measured in.
+	sunIlluminance = 128
+	// skyWidth and skyHeight are the size of the table the sky is sampled from.
+	skyWidth, skyHeight = 128, 64
+	// twilight is the elevation of the sun in degrees below which the sky has gone dark, at the end of
+	// civil twilight.
+	twilight = -6
+)
+
+// SunDirection returns the direction towards the sun seen from a latitude and longitude in degrees at a
+// given time, with north towards -Z, east towards +X and the zenith towards +Y.
+//
+// This follows the approximations of the NOAA solar calculator, accurate to a fraction of a degree over
+// the current century.
+func SunDirection(latitude float64, longitude float64, t time.Time) geometry.Unit {
+	t = t.UTC()
+	hours := float64(t.Hour()) + float64(t.Minute())/60 + float64(t.Second())/3600
+	// The fraction of the year, in radians.
+	g := 2 * math.Pi / 365 * (float64(t.YearDay()-1) + (hours-12)/24)
+	// The minutes the sun runs ahead of the mean sun, and its angle above the equator.
+	eqTime := 229.18 * (0.000075 + 0.001868*math.Cos(g) - 0.032077*math.Sin(g) -
+		0.014615*math.Cos(2*g) - 0.040849*math.Sin(2*g))
+	decl := 0.006918 - 0.399912*math.Cos(g) + 0.070257*math.Sin(g) - 0.006758*math.Cos(2*g) +
+		0.000907*math.Sin(2*g) - 0.002697*math.Cos(3*g) + 0.00148*math.Sin(3*g)
+
+	solarMinutes := hours*60 + eqTime + 4*longitude
+	hourAngle := (solarMinutes/4 - 180) * math.Pi / 180
+	lat := radians(latitude)
+	cosZenith := clamp(math.Sin(lat)*math.Sin(decl)+math.Cos(lat)*math.Cos(decl)*math.Cos(hourAngle), -1, 1)
+	sinZenith := math.Sqrt(1 - cosZenith*cosZenith)
+	// The azimuth runs clockwise from north.
+	azimuth := math.Atan2(math.Sin(hourAngle), math.Cos(hourAngle)*math.Sin(lat)-math.Tan(decl)*math.Cos(lat)) + math.Pi
+	return geometry.NewUnit(sinZenith*math.Sin(azimuth), cosZenith, -sinZenith*math.Cos(azimuth))
+}
+
+// Sky represents the daylight sky and the sun in it, following the analytic model by Preetham et al.
+// The sky is lit by the sun in the direction Sun, and Turbidity measures the haze in the air, from 2 for a
+// very clear sky to 10 for a hazy one. The ground below the horizon reflects the sun and the sky with the
+// GroundAlbedo. Once the sun has set, the sky fades to black as it goes down to 6 degrees below the horizon.
+//
+// The light of the model is measured in thousands of candelas per square meter, with the sun about 100 times
+// brighter than a white surface should be, so Intensity scales it down to the range of the other lights.
+//
+// As a LightSource, the Sky picks either the sun or a direction of the sky in proportion to its light.
+type Sky struct {
+	Sun          geometry.Unit
+	Turbidity    float64
+	GroundAlbedo float64
+	Intensity    float64
+	zenith       [3]float64    // the luminance and chromaticity at the zenith
+	perez        [3][5]float64 // the coefficients of the distribution of the luminance and chromaticity
+	dusk         float64       // the share of the light of the sky left after the sun has set
+	sun          Color         // the light of the sun disk
+	ground       Color         // the light reflected by the ground
+	sunChance    float64       // the share of samples towards the sun
+	table        *Environment  // the sky sampled in proportion to its light
+}
+
+// NewSky returns a new Sky lit by the sun in a direction, with a turbidity from 2 to 10 and the albedo of
+// the ground.
+func NewSky(sun geometry.Unit, turbidity float64, groundAlbedo float64) *Sky {
+	t := clamp(turbidity, 2, 10)
+	s := &Sky{Sun: sun, Turbidity: t, GroundAlbedo: groundAlbedo, Intensity: 0.02}
+	// The model does not hold below the horizon, where the sky is lit as if the sun was setting and dimmed
+	// through the twilight.
+	thetaSun := math.Min(math.Acos(clamp(sun.Y, -1, 1)), math.Pi/2)
+	elevation := 90 - math.Acos(clamp(sun.Y, -1, 1))*180/math.Pi
+	dusk := clamp(1-elevation/twilight, 0, 1)
+	s.dusk = dusk * dusk * (3 - 2*dusk)
+
+	chi := (4.0/9 - t/120) * (math.Pi - 2*thetaSun)
+	s.zenith[0] = (4.0453*t-4.9710)*math.Tan(chi) - 0.2155*t + 2.4192
+	chromaticity := func(m [3][4]float64) float64 {
+		weights := [3]float64{t * t, t, 1}
+		angles := [4]float64{thetaSun * thetaSun * thetaSun, thetaSun * thetaSun, thetaSun, 1}
+		var sum float64
+		for i, row := range m {
+			for j, c := range row {
+				sum += weights[i] * c * angles[j]
+			}
+		}
+		return sum
+	}
+	s.zenith[1] = chromaticity([3][4]float64{
+		{0.00166, -0.00375, 0.00209, 0},
+		{-0.02903, 0.06377, -0.03202, 0.00394},
+		{0.11693, -0.21196, 0.06052, 0.25886},
+	})
+	s.zenith[2] = chromaticity([3][4]float64{
+		{0.00275, -0.00610, 0.00317, 0},
+		{-0.04214, 0.08970, -0.04153, 0.00516},
+		{0.15346, -0.26756, 0.06670, 0.26688},
+	})
+	s.perez = [3][5]float64{
+		{0.1787*t - 1.4630, -0.3554*t + 0.4275, -0.0227*t + 5.3251, 0.1206*t - 2.5771, -0.0670*t + 0.3703},
+		{-0.0193*t - 0.2592, -0.0665*t + 0.0008, -0.0004*t + 0.2125, -0.0641*t - 0.8989, -0.0033*t + 0.0452},
+		{-0.0167*t - 0.2608, -0.0950*t + 0.0092, -0.0079*t + 0.2102, -0.0441*t - 1.6537, -0.0109*t + 0.0529},
+	}
+	// The values at the zenith are those of the model divided by the distribution there.
+	for i := range s.zenith {
+		s.zenith[i] /= perez(s.perez[i], 0, thetaSun)
+	}
+
+	if sun.Y > 0 {
+		s.sun = sunTransmittance(sun.Y, t).Scale(sunIlluminance / sunSolidAngle())
+	}
+
+	// The table of the sky, with the light of the ground added once the light falling on it is known.
+	img := &HDR{Width: skyWidth, Height: skyHeight, Pixels: make([]Color, skyWidth*skyHeight)}
+	var irradiance Color
+	for y := 0; y < skyHeight/2; y++ {
+		for x := 0; x < skyWidth; x++ {
+			dir, sin := fromEquirectangular((float64(x)+0.5)/skyWidth, (float64(y)+0.5)/skyHeight, 0)
+			c := s.sky(dir)
+			img.Pixels[y*skyWidth+x] = c
+			area := sin * (2 * math.Pi / skyWidth) * (math.Pi / skyHeight)
+			irradiance = irradiance.Add(c.Scale(dir.Y * area))
+		}
+	}
+	irradiance = irradiance.Add(s.sun.Scale(math.Max(sun.Y, 0) * sunSolidAngle()))
+	s.ground = irradiance.Scale(groundAlbedo / math.Pi)
+	for i := skyWidth * skyHeight / 2; i < len(img.Pixels); i++ {
+		img.Pixels[i] = s.ground
+	}
+	s.table = NewEnvironment(img, 0, 1)
+
+	// The sun is picked in proportion to its share of the light, leaving some samples for the sky.
+	var total float64
+	for y := 0; y < skyHeight; y++ {
+		sin := math.Sin(math.Pi * (float64(y) + 0.5) / skyHeight)
+		for x := 0; x < skyWidth; x++ {
+			total += img.Pixels[y*skyWidth+x].Luminance() * sin * (2 * math.Pi / skyWidth) * (math.Pi / skyHeight)
+		}
+	}
+	if power := s.sun.Luminance() * sunSolidAngle(); power > 0 {
+		s.sunChance = math.Min(power/(power+total), 0.9)
+	}
+	return s
+}
+
+// Radiance returns the light seen by a ray travelling in the direction dir.
+func (s *Sky) Radiance(dir geometry.Unit) Color {
+	c := s.sky(dir)
+	if dir.Y > 0 && dir.Dot(s.Sun) >= math.Cos(sunRadius) {
+		c = c.Add(s.sun)
+	}
+	return c.Scale(s.Intensity)
+}
+
+// Sample returns the light arriving at p from either the sun or a direction of the sky.
+func (s *Sky) Sample(p geometry.Vec, rnd geometry.Rnd) LightSample {
+	var dir geometry.Unit
+	if rnd.Float64() < s.sunChance {
+		// A direction within the cone of the sun disk.
+		cos := 1 - rnd.Float64()*(1-math.Cos(sunRadius))
+		sin := math.Sqrt(1 - cos*cos)
+		phi := 2 * math.Pi * rnd.Float64()
+		dir = geometry.NewBasis(s.Sun).World(geometry.NewVec(sin*math.Cos(phi), sin*math.Sin(phi), cos)).ToUnit()
+	} else {
+		dir = s.table.Sample(p, rnd).Direction
+	}
+	sample := LightSample{Direction: dir, Distance: math.Inf(1), Light: Black}
+	if sample.PDF = s.PDF(dir); sample.PDF > 0 {
+		sample.Light = s.Radiance(dir).Scale(1 / sample.PDF)
+	}
+	return sample
+}
+
+// PDF returns the density with which Sample picks the direction dir towards the sky.
+func (s *Sky) PDF(dir geometry.Unit) float64 {
+	pdf := (1 - s.sunChance) * s.table.PDF(dir)
+	if dir.Dot(s.Sun) >= math.Cos(sunRadius) {
+		pdf += s.sunChance / sunSolidAngle()
+	}
+	return pdf
+}
+
+// sky returns the light of the sky in the direction dir, without the sun disk.
+func (s *Sky) sky(dir geometry.Unit) Color {
+	if dir.Y < 0 {
+		return s.ground
+	}
+	// Directions along the horizon are taken slightly above it, where the model is bounded.
+	theta := math.Acos(math.Max(dir.Y, 0.01))
+	gamma := math.Acos(clamp(dir.Dot(s.Sun), -1, 1))
+	luminance := s.zenith[0] * perez(s.perez[0], theta, gamma)
+	x := s.zenith[1] * perez(s.perez[1], theta, gamma)
+	y := s.zenith[2] * perez(s.perez[2], theta, gamma)
+	if y <= 0 || luminance <= 0 {
+		return Black
+	}
+	rgb := xyzToRGB(geometry.NewVec(x/y*luminance, luminance, (1-x-y)/y*luminance))
+	return NewColor(math.Max(rgb.X, 0), math.Max(rgb.Y, 0), math.Max(rgb.Z, 0)).Scale(s.dusk)
+}
+
+// perez returns the distribution of the Perez model for coefficients, at an angle theta from the zenith
+// and gamma from the sun.
+func perez(c [5]float64, theta float64, gamma float64) float64 {
+	cos := math.Cos(gamma)
+	return (1 + c[0]*math.Exp(c[1]/math.Cos(theta))) * (1 + c[2]*math.Exp(c[3]*gamma) + c[4]*cos*cos)
+}
+
+// sunTransmittance returns the share of the light of the sun at a height cosY above the horizon going
+// through the atmosphere, scattered by air molecules and by the haze of a turbidity.
+func sunTransmittance(cosY float64, turbidity float64) Color {
+	// The relative length of air crossed, following Kasten and Young.
+	elevation := 90 - math.Acos(cosY)*180/math.Pi
+	mass := 1 / (cosY + 0.50572*math.Pow(elevation+6.07995, -1.6364))
+	beta := 0.04608*turbidity - 0.04586
+	channel := func(wavelength float64) float64 {
+		// The optical depths of Rayleigh scattering and of the aerosols, for a wavelength in micrometers.
+		depth := 0.008735*math.Pow(wavelength, -4.08) + beta*math.Pow(wavelength, -1.3)
+		return math.Exp(-mass * depth)
+	}
+	return NewColor(channel(0.65), channel(0.55), channel(0.45))
+}
+
+// sunSolidAngle returns the solid angle of the sun disk.
+func sunSolidAngle() float64 {
+	return 2 * math.Pi * (1 - math.Cos(sunRadius))
+}
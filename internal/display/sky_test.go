@@ -0,0 +1,92 @@
+package display
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+func TestSunDirection(t *testing.T) {
+	tests := []struct {
+		name      string
+		latitude  float64
+		longitude float64
+		time      string
+		elevation float64
+		azimuth   float64 // clockwise from north
+	}{
+		{name: "summer noon", latitude: 40, longitude: 0, time: "2024-06-21T12:02:00Z", elevation: 73.44, azimuth: 180},
+		{name: "winter noon", latitude: 40, longitude: 0, time: "2024-12-21T11:58:00Z", elevation: 26.56, azimuth: 180},
+		{name: "southern winter noon", latitude: -40, longitude: 0, time: "2024-06-21T12:02:00Z", elevation: 26.56, azimuth: 0},
+		{name: "equinox sunrise", latitude: 0, longitude: 0, time: "2024-03-20T06:07:00Z", elevation: 0, azimuth: 90},
+		{name: "equinox sunset", latitude: 0, longitude: 0, time: "2024-03-20T18:07:00Z", elevation: 0, azimuth: 270},
+		{name: "east of greenwich", latitude: 0, longitude: 90, time: "2024-03-20T06:07:00Z", elevation: 90, azimuth: 0},
+		{name: "local time zone", latitude: 0, longitude: 90, time: "2024-03-20T08:07:00+02:00", elevation: 90, azimuth: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at, err := time.Parse(time.RFC3339, tt.time)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := SunDirection(tt.latitude, tt.longitude, at)
+			elevation := radians(tt.elevation)
+			azimuth := radians(tt.azimuth)
+			want := geometry.NewUnit(math.Cos(elevation)*math.Sin(azimuth), math.Sin(elevation), -math.Cos(elevation)*math.Cos(azimuth))
+			if angle := math.Acos(clamp(got.Dot(want), -1, 1)); angle > radians(0.5) {
+				t.Errorf("SunDirection() = %v, want %v, %.2f degrees apart", got, want, angle*180/math.Pi)
+			}
+		})
+	}
+
+	// Both sides of the date line are the same place, late in the day when the sun runs ahead of its mean.
+	at := time.Date(2024, 11, 3, 23, 59, 0, 0, time.UTC)
+	east, west := SunDirection(30, 180, at), SunDirection(30, -180, at)
+	if angle := math.Acos(clamp(east.Dot(west), -1, 1)); angle > 1e-6 {
+		t.Errorf("SunDirection() = %v east of the date line and %v west of it", east, west)
+	}
+}
+
+func TestSky(t *testing.T) {
+	tests := []struct {
+		name      string
+		sun       geometry.Unit
+		turbidity float64
+		dark      bool
+	}{
+		{name: "clear noon", sun: geometry.NewVec(0.2, 1, 0.3).ToUnit(), turbidity: 2.5},
+		{name: "hazy afternoon", sun: geometry.NewVec(1, 0.5, -0.2).ToUnit(), turbidity: 8},
+		{name: "sunset", sun: geometry.NewVec(0, 0.05, 1).ToUnit(), turbidity: 4},
+		{name: "twilight", sun: geometry.NewVec(0, -0.05, 1).ToUnit(), turbidity: 3},
+		{name: "night", sun: geometry.NewVec(0, -0.3, 1).ToUnit(), turbidity: 3, dark: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sky := NewSky(tt.sun, tt.turbidity, 0.3)
+			if tt.dark {
+				if c := sky.Radiance(geometry.NewUnit(0, 1, 0)); c != Black {
+					t.Errorf("Radiance() at the zenith = %v, want a dark sky", c)
+				}
+				return
+			}
+			if c := sky.Radiance(geometry.NewUnit(0, 1, 0)); c.Blue() <= c.Red() {
+				t.Errorf("Radiance() at the zenith = %v, want a blue sky", c)
+			}
+
+			// The light of the sky integrated over the sphere, with the tiny sun disk counted apart.
+			irradiance := sphereIntegral(func(dir geometry.Unit) float64 {
+				return sky.sky(dir).Red() * sky.Intensity
+			})
+			irradiance += sky.sun.Red() * sky.Intensity * sunSolidAngle()
+			density := sphereIntegral(func(dir geometry.Unit) float64 {
+				return (1 - sky.sunChance) * sky.table.PDF(dir)
+			})
+			if density += sky.sunChance; math.Abs(density-1) > 1e-2 {
+				t.Errorf("PDF() integrates to %v, want 1", density)
+			}
+			checkSampling(t, sky, irradiance, 50000, 2e-2)
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package display
+
+import (
+	"math"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+// Spectral rendering traces each path at three wavelengths instead of the red, green and blue channels.
+// The values of a Color then hold the light carried at each of the ray's Wavelengths, and are converted
+// back to RGB once the path is complete. Materials and textures keep working in RGB and their colors
+// are turned into spectra at the wavelengths of each ray.
+//
+// One wavelength, the hero, is picked at random for each path and the two others are spread evenly
+// around the visible range from it, following "Hero Wavelength Spectral Sampling" by Wilkie et al.
+// When a material scatters each wavelength in a different direction, such as a dispersive Dielectric,
+// the path can only follow one of them: the other two are dropped and the hero carries their weight.
+
+const (
+	minWavelength = 380.0
+	maxWavelength = 720.0
+)
+
+// SampleWavelengths returns the three wavelengths carried by a spectral ray, from a random number u in [0, 1).
+func SampleWavelengths(u float64) geometry.Vec {
+	span := maxWavelength - minWavelength
+	hero := u * span
+	rotate := func(i float64) float64 {
+		return minWavelength + math.Mod(hero+i*span/3, span)
+	}
+	return geometry.NewVec(rotate(0), rotate(1), rotate(2))
+}
+
+// Spectral returns a Color as seen by the ray r: unchanged when rendering in RGB, or sampled at
+// the ray's wavelengths when rendering spectrally.
+func Spectral(c Color, r *geometry.Ray) Color {
+	if r.Wavelengths.Zero() {
+		return c
+	}
+	return NewColor(
+		rgbToSpectrum(c, r.Wavelengths.X),
+		rgbToSpectrum(c, r.Wavelengths.Y),
+		rgbToSpectrum(c, r.Wavelengths.Z),
+	)
+}
+
+// CarryWavelengths passes the wavelengths of the ray in on to the ray out it was scattered into, and returns
+// the attenuation of the scattering as seen by the ray in.
+//
+// Materials that set the wavelengths of out themselves work on the wavelengths directly, and their attenuation
+// is already given at each wavelength. When such a material dropped the secondary wavelengths from out,
+// the attenuation moves their weight onto the hero wavelength. Materials that scatter the ray in itself, such
+// as Isotropic, pass its wavelengths on unchanged and still give an RGB attenuation.
+func CarryWavelengths(in *geometry.Ray, attenuation Color, out *geometry.Ray) Color {
+	if in.Wavelengths.Zero() {
+		return attenuation
+	}
+	if out == in || out.Wavelengths.Zero() {
+		out.Wavelengths = in.Wavelengths
+		return Spectral(attenuation, in)
+	}
+	if in.Wavelengths.Y != 0 && out.Wavelengths.Y == 0 {
+		return attenuation.Mul(NewColor(3, 0, 0))
+	}
+	return attenuation
+}
+
+// SpectralToRGB converts the light carried by a spectral path at the given wavelengths into an RGB Color.
+//
+// Each wavelength contributes to the CIE XYZ color through the color matching functions, which is then
+// converted to linear sRGB. White is balanced so that a flat spectrum gives equal red, green and blue.
+func SpectralToRGB(c Color, wavelengths geometry.Vec) Color {
+	xyz := geometry.Vec{}
+	values := []float64{c.Red(), c.Green(), c.Blue()}
+	for i, lambda := range []float64{wavelengths.X, wavelengths.Y, wavelengths.Z} {
+		if lambda == 0 {
+			continue
+		}
+		xyz = xyz.Add(colorMatch(lambda).Scale(values[i]))
+	}
+	// Each wavelength is sampled uniformly over the visible range.
+	xyz = xyz.Scale((maxWavelength - minWavelength) / 3 / spectralWhite.y)
+	return Color{Vec: xyzToRGB(xyz).Div(spectralWhite.rgb)}
+}
+
+// spectralWhite holds the integral of the color matching functions over the visible range, used to normalise
+// the luminance of spectral paths and to balance the white of a flat spectrum.
+var spectralWhite = func() struct {
+	y   float64
+	rgb geometry.Vec
+} {
+	xyz := geometry.Vec{}
+	for lambda := minWavelength; lambda < maxWavelength; lambda++ {
+		xyz = xyz.Add(colorMatch(lambda + 0.5))
+	}
+	return struct {
+		y   float64
+		rgb geometry.Vec
+	}{y: xyz.Y, rgb: xyzToRGB(xyz.Scale(1 / xyz.Y))}
+}()
+
+// colorMatch returns the CIE 1931 color matching functions at a wavelength in nanometres, using the
+// multi-lobe fit from "Simple Analytic Approximations to the CIE XYZ Color Matching Functions" by Wyman et al.
+func colorMatch(lambda float64) geometry.Vec {
+	g := func(mu float64, sigma1 float64, sigma2 float64) float64 {
+		sigma := sigma2
+		if lambda < mu {
+			sigma = sigma1
+		}
+		t := (lambda - mu) / sigma
+		return math.Exp(-0.5 * t * t)
+	}
+	return geometry.NewVec(
+		1.056*g(599.8, 37.9, 31.0)+0.362*g(442.0, 16.0, 26.7)-0.065*g(501.1, 20.4, 26.2),
+		0.821*g(568.8, 46.9, 40.5)+0.286*g(530.9, 16.3, 31.1),
+		1.217*g(437.0, 11.8, 36.0)+0.681*g(459.0, 26.0, 13.8),
+	)
+}
+
+// xyzToRGB converts a CIE XYZ color to linear sRGB.
+func xyzToRGB(xyz geometry.Vec) geometry.Vec {
+	return geometry.NewVec(
+		3.2404542*xyz.X-1.5371385*xyz.Y-0.4985314*xyz.Z,
+		-0.9692660*xyz.X+1.8760108*xyz.Y+0.0415560*xyz.Z,
+		0.0556434*xyz.X-0.2040259*xyz.Y+1.0572252*xyz.Z,
+	)
+}
+
+// The spectra of Smits' "An RGB to Spectrum Conversion for Reflectances", sampled in ten bins
+// spread evenly over the visible range.
+var (
+	smitsWhite   = [10]float64{1.0000, 1.0000, 0.9999, 0.9993, 0.9992, 0.9998, 1.0000, 1.0000, 1.0000, 1.0000}
+	smitsCyan    = [10]float64{0.9710, 0.9426, 1.0007, 1.0007, 1.0007, 1.0007, 0.1564, 0.0000, 0.0000, 0.0000}
+	smitsMagenta = [10]float64{1.0000, 1.0000, 0.9685, 0.2229, 0.0000, 0.0458, 0.8369, 1.0000, 1.0000, 0.9959}
+	smitsYellow  = [10]float64{0.0001, 0.0000, 0.1088, 0.6651, 1.0000, 1.0000, 0.9996, 0.9586, 0.9685, 0.9840}
+	smitsRed     = [10]float64{0.1012, 0.0515, 0.0000, 0.0000, 0.0000, 0.0000, 0.8325, 1.0149, 1.0149, 1.0149}
+	smitsGreen   = [10]float64{0.0000, 0.0000, 0.0273, 0.7937, 1.0000, 0.9418, 0.1719, 0.0000, 0.0000, 0.0025}
+	smitsBlue    = [10]float64{1.0000, 1.0000, 0.8916, 0.3323, 0.0000, 0.0000, 0.0003, 0.0369, 0.0483, 0.0496}
+)
+
+// rgbToSpectrum returns the value at a wavelength of a smooth spectrum matching an RGB Color.
+//
+// The spectrum is built with Smits' method, from white plus the primary and secondary colors needed
+// to reach the color. Colors brighter than white are scaled versions of the same spectra.
+func rgbToSpectrum(c Color, lambda float64) float64 {
+	bin := int((lambda - minWavelength) / (maxWavelength - minWavelength) * 10)
+	bin = clampIndex(bin, 9)
+	r, g, b := c.Red(), c.Green(), c.Blue()
+	switch {
+	case r <= g && r <= b:
+		if g <= b {
+			return r*smitsWhite[bin] + (g-r)*smitsCyan[bin] + (b-g)*smitsBlue[bin]
+		}
+		return r*smitsWhite[bin] + (b-r)*smitsCyan[bin] + (g-b)*smitsGreen[bin]
+	case g <= r && g <= b:
+		if r <= b {
+			return g*smitsWhite[bin] + (r-g)*smitsMagenta[bin] + (b-r)*smitsBlue[bin]
+		}
+		return g*smitsWhite[bin] + (b-g)*smitsMagenta[bin] + (r-b)*smitsRed[bin]
+	default:
+		if r <= g {
+			return b*smitsWhite[bin] + (r-b)*smitsYellow[bin] + (g-r)*smitsGreen[bin]
+		}
+		return b*smitsWhite[bin] + (g-b)*smitsYellow[bin] + (r-g)*smitsRed[bin]
+	}
+}
+
+// RefractiveIndex represents an index of refraction that varies with the wavelength of light.
+type RefractiveIndex interface {
+	// At returns the index of refraction at a wavelength in nanometres.
+	At(wavelength float64) float64
+}
+
+// Cauchy represents a RefractiveIndex following Cauchy's equation n = A + B / λ², with λ in micrometres.
+type Cauchy struct {
+	A float64
+	B float64
+}
+
+// At returns the index of refraction at a wavelength in nanometres.
+func (c Cauchy) At(wavelength float64) float64 {
+	micro := wavelength / 1000
+	return c.A + c.B/(micro*micro)
+}
+
+// Sellmeier represents a RefractiveIndex following the Sellmeier equation n² = 1 + Σ Bᵢλ² / (λ² - Cᵢ),
+// with λ in micrometres.
+type Sellmeier struct {
+	B [3]float64
+	C [3]float64
+}
+
+// At returns the index of refraction at a wavelength in nanometres.
+func (s Sellmeier) At(wavelength float64) float64 {
+	micro2 := wavelength * wavelength / 1e6
+	n2 := 1.0
+	for i := range s.B {
+		n2 += s.B[i] * micro2 / (micro2 - s.C[i])
+	}
+	return math.Sqrt(n2)
+}
+
+// Glasses holds the dispersion of common transparent materials.
+var Glasses = map[string]RefractiveIndex{
+	"bk7":          Sellmeier{B: [3]float64{1.03961212, 0.231792344, 1.01046945}, C: [3]float64{0.00600069867, 0.0200179144, 103.560653}},
+	"diamond":      Sellmeier{B: [3]float64{4.3356, 0.3306, 0}, C: [3]float64{0.011236, 0.030625, 0}},
+	"fused silica": Sellmeier{B: [3]float64{0.6961663, 0.4079426, 0.8974794}, C: [3]float64{0.00467914826, 0.0135120631, 97.9340025}},
+	"sf11":         Sellmeier{B: [3]float64{1.73759695, 0.313747346, 1.89878101}, C: [3]float64{0.013188707, 0.0623068142, 155.23629}},
+	"water":        Cauchy{A: 1.3199, B: 0.00653},
+}
@@ -0,0 +1,149 @@
+package display
+
+import (
+	"math"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+// maxWalk is the number of scattering events after which a random walk inside a Subsurface gives up.
+const maxWalk = 256
+
+// Subsurface contains a closed HitBoxer, such as a Sphere, filled with a translucent material where light
+// scatters many times before leaving, such as skin, wax, milk or marble.
+//
+// Light is refracted into the surface and follows a random walk through the inside, like the rays scattered
+// in a Volume, until it leaves through the surface again. Albedo is the color of the material as seen from
+// afar, after all the scattering, and MeanFreePath is the average distance light travels between scattering
+// events in each channel, which sets how far each color bleeds through the material.
+type Subsurface struct {
+	Boundary     HitBoxer
+	Albedo       Texture
+	MeanFreePath Color
+	RefIndex     float64
+	Roughness    Texture
+}
+
+// NewSubsurface returns a new Subsurface filling a boundary, with a given albedo and mean free path.
+func NewSubsurface(boundary HitBoxer, albedo Texture, meanFreePath Color) *Subsurface {
+	return &Subsurface{Boundary: boundary, Albedo: albedo, MeanFreePath: meanFreePath, RefIndex: 1.4}
+}
+
+// Hit finds the first intersection between a ray and the boundary, which scatters light through the inside.
+func (s *Subsurface) Hit(r *geometry.Ray, tMin float64, tMax float64) (bool, *HitRecord) {
+	hit, rec := s.Boundary.Hit(r, tMin, tMax)
+	if hit {
+		rec.Material = randomWalk{s}
+	}
+	return hit, rec
+}
+
+// Box returns the bounding box of the boundary.
+func (s *Subsurface) Box(t0 float64, t1 float64) *AABB {
+	return s.Boundary.Box(t0, t1)
+}
+
+// randomWalk represents the Material of the boundary of a Subsurface.
+type randomWalk struct {
+	*Subsurface
+}
+
+// Emit returns Black.
+func (w randomWalk) Emit(r *geometry.Ray, rec *HitRecord) Color {
+	return Black
+}
+
+// Scatter reflects light rays off the surface, or refracts them inside and walks them through the material
+// until they leave it. Returns the ray leaving the surface along with the light left after the walk.
+func (w randomWalk) Scatter(r *geometry.Ray, rec *HitRecord) (bool, *Color, *geometry.Ray) {
+	roughness := intensity(w.Roughness, rec)
+	dist := newGGX(roughness, roughness)
+	basis, wo, back := localFrame(r, rec)
+	eta := w.RefIndex
+	if back {
+		eta = 1 / eta
+	}
+	wi, weight, ok := scatterDielectric(wo, dist, eta, r.Rnd)
+	if !ok {
+		return false, &Color{}, &geometry.Ray{}
+	}
+	throughput := White.Scale(weight)
+	ray := scatterLocal(r, rec, basis, wi)
+	if wi.Z > 0 {
+		// Reflected off the surface.
+		return true, &throughput, ray
+	}
+
+	sigmaT, sigmaS := w.coefficients(rec)
+	for i := 0; i < maxWalk; i++ {
+		// Sample the distance to the next scattering event in one of the channels, picked in proportion to the
+		// light it still carries, and weigh the result over the chances of sampling it from any of the channels.
+		// This keeps the weights from growing when the channels scatter at very different rates.
+		total := throughput.Vec.Dot(geometry.NewVec(1, 1, 1))
+		if total <= 0 {
+			return false, &Color{}, &geometry.Ray{}
+		}
+		chances := throughput.Scale(1 / total)
+		pick := r.Rnd.Float64()
+		sigma := sigmaT.Blue()
+		if pick < chances.Red() {
+			sigma = sigmaT.Red()
+		} else if pick < chances.Red()+chances.Green() {
+			sigma = sigmaT.Green()
+		}
+		d := -math.Log(1-r.Rnd.Float64()) / sigma
+
+		exit, out := w.Boundary.Hit(ray, bias, math.MaxFloat64)
+		if !exit {
+			return false, &Color{}, &geometry.Ray{}
+		}
+		if d < out.t {
+			transmittance := extinction(sigmaT, d)
+			pdf := chances.Vec.Dot(sigmaT.Mul(transmittance).Vec)
+			throughput = throughput.Mul(sigmaS.Mul(transmittance)).Scale(1 / pdf)
+			ray = geometry.NewRay(ray.At(d), UniformPhase{}.Sample(ray.Direction, r.Rnd), r.Time, r.Rnd)
+			continue
+		}
+
+		transmittance := extinction(sigmaT, out.t)
+		pdf := chances.Vec.Dot(transmittance.Vec)
+		throughput = throughput.Mul(transmittance).Scale(1 / pdf)
+
+		// Cross the surface back out, or reflect off it back into the material.
+		basis, wo, _ := localFrame(ray, out)
+		wi, weight, ok := scatterDielectric(wo, dist, 1/w.RefIndex, r.Rnd)
+		if !ok {
+			return false, &Color{}, &geometry.Ray{}
+		}
+		throughput = throughput.Scale(weight)
+		ray = scatterLocal(ray, out, basis, wi)
+		if wi.Z < 0 {
+			return true, &throughput, ray
+		}
+	}
+	return false, &Color{}, &geometry.Ray{}
+}
+
+// coefficients returns the extinction and scattering coefficients of the material that give the albedo
+// at the hit, using the inversion from "Practical and Controllable Subsurface Scattering for Production
+// Path Tracing" by Chiang et al.
+func (w randomWalk) coefficients(rec *HitRecord) (Color, Color) {
+	albedo := w.Albedo.At(rec.u, rec.v, rec.p)
+	single := func(a float64) float64 {
+		a = clamp(a, 0, 0.999)
+		s := 4.09712 + 4.20863*a - math.Sqrt(9.59217+41.6808*a+17.7126*a*a)
+		return 1 - s*s
+	}
+	coefficient := func(mfp float64) float64 {
+		return 1 / math.Max(mfp, 1e-6)
+	}
+	sigmaT := NewColor(coefficient(w.MeanFreePath.Red()), coefficient(w.MeanFreePath.Green()), coefficient(w.MeanFreePath.Blue()))
+	alpha := NewColor(single(albedo.Red()), single(albedo.Green()), single(albedo.Blue()))
+	return sigmaT, sigmaT.Mul(alpha)
+}
+
+// extinction returns the fraction of light of each channel left after a distance d through a material
+// with the extinction coefficients sigmaT.
+func extinction(sigmaT Color, d float64) Color {
+	return NewAbsorbing(sigmaT).Transmittance(d)
+}
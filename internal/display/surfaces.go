@@ -69,6 +69,46 @@ type HitRecord struct {
 	Material Material      // the material associated to this record
 	u        float64       // surface coordinate
 	v        float64       // surface coordinate
+	dpdu     geometry.Vec  // derivative of the point along u, or zero when the surface has no tangents
+	dpdv     geometry.Vec  // derivative of the point along v
+	mirrored bool          // whether dpdv runs against v, on surfaces seen from their back
+	outerIOR float64       // index of refraction on the far side of the surface from its material, or 0 for air
+}
+
+// flip turns the HitRecord around to the back of its surface. The normal and dpdv are inverted so that the
+// tangent frame stays right-handed, while the coordinates and the textures laid on them stay in place.
+func (hr *HitRecord) flip() {
+	hr.normal = hr.normal.Inv()
+	hr.dpdv = hr.dpdv.Inv()
+	hr.mirrored = !hr.mirrored
+}
+
+// tangents returns the derivatives of the point along u and v. Surfaces without tangents get an arbitrary pair
+// of unit tangents around the normal.
+func (hr *HitRecord) tangents() (geometry.Vec, geometry.Vec) {
+	if hr.dpdu.Zero() || hr.dpdv.Zero() {
+		basis := geometry.NewBasis(hr.normal)
+		return basis.U.Vec, basis.V.Vec
+	}
+	return hr.dpdu, hr.dpdv
+}
+
+// outer returns the index of refraction on the far side of the surface from its material.
+func (hr *HitRecord) outer() float64 {
+	if hr.outerIOR == 0 {
+		return 1
+	}
+	return hr.outerIOR
+}
+
+// T returns the distance along the ray at which the hit occurred.
+func (hr *HitRecord) T() float64 {
+	return hr.t
+}
+
+// P returns the point at which the hit occurred.
+func (hr *HitRecord) P() geometry.Vec {
+	return hr.p
 }
 
 func NewBVH(depth int, time0 float64, time1 float64, h ...HitBoxer) *BVH {
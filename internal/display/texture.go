@@ -2,10 +2,12 @@ package display
 
 import (
 	"image"
+	"image/color"
 	"io"
 	"math"
 
 	_ "image/jpeg"
+	_ "image/png"
 
 	"github.com/lucasmelin/raytracer/internal/geometry"
 )
@@ -133,6 +135,23 @@ func NewImage(rc io.ReadCloser) (*Image, error) {
 }
 
 func (i *Image) At(u float64, v float64, p geometry.Vec) Color {
+	r, g, b, a := i.texel(u, v).RGBA()
+	if a == 0 {
+		return Black
+	}
+	// Colors are stored premultiplied by their alpha, which would darken the edges of cutouts.
+	divisor := float64(a)
+	return NewColor(float64(r)/divisor, float64(g)/divisor, float64(b)/divisor)
+}
+
+// Alpha returns the opacity of the image at the given surface coordinates, from 0 for fully transparent to 1.
+func (i *Image) Alpha(u float64, v float64) float64 {
+	_, _, _, a := i.texel(u, v).RGBA()
+	return float64(a) / 65535
+}
+
+// texel returns the color of the pixel at the given surface coordinates.
+func (i *Image) texel(u float64, v float64) color.Color {
 	x := int(u * float64(i.X))
 	y := int((1 - v) * float64(i.Y))
 	if x < 0 {
@@ -147,8 +166,21 @@ func (i *Image) At(u float64, v float64, p geometry.Vec) Color {
 	if y > i.Y-1 {
 		y = i.Y - 1
 	}
-	c := i.Data.At(x, y)
-	r, g, b, _ := c.RGBA()
-	divisor := float64(65535)
-	return NewColor(float64(r)/divisor, float64(g)/divisor, float64(b)/divisor)
+	return i.Data.At(x, y)
+}
+
+// AlphaMask represents a grayscale Texture of the opacity of an Image, for use as a mask.
+type AlphaMask struct {
+	Image *Image
+}
+
+// NewAlphaMask returns a new AlphaMask of the alpha channel of an image.
+func NewAlphaMask(img *Image) AlphaMask {
+	return AlphaMask{Image: img}
+}
+
+// At returns the opacity of the image as a shade of gray.
+func (a AlphaMask) At(u float64, v float64, p geometry.Vec) Color {
+	alpha := a.Image.Alpha(u, v)
+	return NewColor(alpha, alpha, alpha)
 }
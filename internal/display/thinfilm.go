@@ -0,0 +1,263 @@
+package display
+
+import (
+	"math"
+	"math/cmplx"
+
+	"github.com/lucasmelin/raytracer/internal/geometry"
+)
+
+// ThinFilm represents a film a few hundred nanometres thick coating a Base material, such as the oil on a
+// puddle, the oxide on heated metal or the walls of a soap bubble.
+//
+// Light reflected off both sides of the film interferes, making some wavelengths reflect more and others less
+// depending on the thickness of the film and the angle it is seen at, which gives iridescent colors.
+// The reflection and transmission of bases following the Fresnel equations, such as a Dielectric or a Conductor,
+// are scaled by how much the film changes their reflectance. Other bases, such as a Lambertian, are covered by
+// the film as by a varnish. Without a Base, the film stands on its own with air on both sides, like a soap bubble.
+//
+// Thickness is given in nanometres and may be varied over the surface by the grayscale ThicknessMap.
+type ThinFilm struct {
+	Base         Material
+	Thickness    float64
+	ThicknessMap Texture
+	RefIndex     float64
+}
+
+// NewThinFilm creates a new ThinFilm coating a base material, with a given thickness in nanometres and
+// index of refraction.
+func NewThinFilm(base Material, thickness float64, refIndex float64) ThinFilm {
+	return ThinFilm{Base: base, Thickness: thickness, RefIndex: refIndex}
+}
+
+// NewSoapBubble creates a new ThinFilm of soapy water standing on its own.
+func NewSoapBubble(thickness float64) ThinFilm {
+	return NewThinFilm(nil, thickness, 1.33)
+}
+
+// Emit returns the light emitted by the base material.
+func (f ThinFilm) Emit(r *geometry.Ray, rec *HitRecord) Color {
+	if f.Base == nil {
+		return Black
+	}
+	return f.Base.Emit(r, rec)
+}
+
+// wrapped returns the base material under the film, which is nil for a soap bubble.
+func (f ThinFilm) wrapped() []Material {
+	return []Material{f.Base}
+}
+
+// Scatter scatters light rays off the base material, tinted by the interference in the film.
+func (f ThinFilm) Scatter(r *geometry.Ray, rec *HitRecord) (bool, *Color, *geometry.Ray) {
+	thickness := f.thickness(rec)
+	wavelengths := r.Wavelengths
+	if wavelengths.Zero() {
+		wavelengths = rgbWavelengths
+	}
+
+	if f.Base == nil {
+		// Light that is not reflected goes straight through the film.
+		return f.coat(r, rec, thickness, wavelengths, 1, func() (bool, *Color, *geometry.Ray) {
+			return true, &White, geometry.NewRay(rec.p, r.Direction, r.Time, r.Rnd)
+		})
+	}
+	substrate, ok := substrateIOR(f.Base, rec)
+	if !ok {
+		// The base has no reflection of its own for the film to change, such as a Lambertian,
+		// so the film reflects light on top of it.
+		return f.coat(r, rec, thickness, wavelengths, 1.5, func() (bool, *Color, *geometry.Ray) {
+			return f.Base.Scatter(r, rec)
+		})
+	}
+
+	wasScattered, attenuation, scattered := f.Base.Scatter(r, rec)
+	wo := r.Direction.Inv()
+	if !wasScattered || wo.Dot(rec.normal) < 0 {
+		// The film only coats the outside of the surface.
+		return wasScattered, attenuation, scattered
+	}
+	tint := spectralTint(r, *attenuation, scattered)
+
+	reflected := scattered.Direction.Dot(rec.normal) > 0
+	cos := wo.Dot(rec.normal)
+	if reflected {
+		cos = wo.Vec.Dot(wo.Add(scattered.Direction.Vec).ToUnit().Vec)
+	}
+	tint = tint.Mul(f.fresnelScale(substrate, cos, thickness, reflected, wavelengths, !r.Wavelengths.Zero()))
+	return true, &tint, scattered
+}
+
+// Evaluate returns the light scattered from wi by the base material, changed by the film as in Scatter.
+// The film is evaluated in RGB, and a soap bubble only mirrors or passes light, which direct light cannot reach.
+func (f ThinFilm) Evaluate(r *geometry.Ray, rec *HitRecord, wi geometry.Unit) (Color, float64) {
+	if f.Base == nil {
+		return Black, 0
+	}
+	value, pdf := evaluate(f.Base, r, rec, wi)
+	if pdf <= 0 {
+		return value, pdf
+	}
+	thickness := f.thickness(rec)
+	wo := r.Direction.Inv()
+	substrate, ok := substrateIOR(f.Base, rec)
+	if !ok {
+		reflect, chance := f.reflectance(math.Abs(wo.Dot(rec.normal)), thickness, rgbWavelengths, 1.5)
+		return value.Mul(NewColor(1-reflect.Red(), 1-reflect.Green(), 1-reflect.Blue())), pdf * (1 - chance)
+	}
+	if wo.Dot(rec.normal) < 0 {
+		return value, pdf
+	}
+	reflected := wi.Dot(rec.normal) > 0
+	cos := wo.Dot(rec.normal)
+	if reflected {
+		cos = wo.Vec.Dot(wo.Add(wi.Vec).ToUnit().Vec)
+	}
+	return value.Mul(f.fresnelScale(substrate, cos, thickness, reflected, rgbWavelengths, false)), pdf
+}
+
+// thickness returns the thickness of the film at a hit, in nanometres.
+func (f ThinFilm) thickness(rec *HitRecord) float64 {
+	if f.ThicknessMap != nil {
+		return f.Thickness * intensity(f.ThicknessMap, rec)
+	}
+	return f.Thickness
+}
+
+// fresnelScale returns how much the film changes the reflectance of a substrate, or its transmittance when
+// the light is not reflected, at each of the wavelengths for light arriving at an angle whose cosine is cos.
+// When rendering in RGB rather than spectrally, the wavelengths stand in for the channels of the substrate.
+func (f ThinFilm) fresnelScale(substrate ComplexIOR, cos float64, thickness float64, reflected bool, wavelengths geometry.Vec, spectral bool) Color {
+	scale := func(i int, lambda float64) float64 {
+		if lambda == 0 {
+			return 0
+		}
+		var sub complex128
+		if spectral {
+			sub = complex(rgbToSpectrum(substrate.Eta, lambda), rgbToSpectrum(substrate.K, lambda))
+		} else {
+			sub = complex(channel(substrate.Eta, i), channel(substrate.K, i))
+		}
+		film := airy(cos, lambda, f.RefIndex, thickness, sub)
+		bare := airy(cos, lambda, f.RefIndex, 0, sub)
+		if reflected {
+			return film / math.Max(bare, 1e-6)
+		}
+		return (1 - film) / math.Max(1-bare, 1e-6)
+	}
+	return NewColor(scale(0, wavelengths.X), scale(1, wavelengths.Y), scale(2, wavelengths.Z))
+}
+
+// reflectance returns the share of light reflected by the film over a substrate at each of the wavelengths,
+// and the chance of coat picking the reflection.
+func (f ThinFilm) reflectance(cos float64, thickness float64, wavelengths geometry.Vec, substrate complex128) (Color, float64) {
+	reflectance := func(lambda float64) float64 {
+		if lambda == 0 {
+			return 0
+		}
+		return airy(cos, lambda, f.RefIndex, thickness, substrate)
+	}
+	reflect := NewColor(reflectance(wavelengths.X), reflectance(wavelengths.Y), reflectance(wavelengths.Z))
+
+	// Pick between reflection and transmission in proportion to the average reflectance.
+	channels := 3.0
+	if wavelengths.Y == 0 {
+		channels = 1
+	}
+	return reflect, clamp(reflect.Vec.Dot(geometry.NewVec(1, 1, 1))/channels, 1e-3, 1-1e-3)
+}
+
+// coat mirrors light rays off a film lying over a substrate with the given index of refraction, or passes
+// them on to under, which scatters the light making it through the film.
+func (f ThinFilm) coat(r *geometry.Ray, rec *HitRecord, thickness float64, wavelengths geometry.Vec, substrate complex128, under func() (bool, *Color, *geometry.Ray)) (bool, *Color, *geometry.Ray) {
+	n := rec.normal
+	cos := -r.Direction.Dot(n)
+	if cos < 0 {
+		n = n.Inv()
+		cos = -cos
+	}
+	reflect, chance := f.reflectance(cos, thickness, wavelengths, substrate)
+	if r.Rnd.Float64() < chance {
+		tint := reflect.Scale(1 / chance)
+		scattered := geometry.NewRay(rec.p, r.Direction.Reflect(n), r.Time, r.Rnd)
+		if !r.Wavelengths.Zero() {
+			scattered.Wavelengths = r.Wavelengths
+		}
+		return true, &tint, scattered
+	}
+
+	wasScattered, attenuation, scattered := under()
+	if !wasScattered {
+		return wasScattered, attenuation, scattered
+	}
+	tint := spectralTint(r, *attenuation, scattered)
+	tint = tint.Mul(NewColor(1-reflect.Red(), 1-reflect.Green(), 1-reflect.Blue())).Scale(1 / (1 - chance))
+	return true, &tint, scattered
+}
+
+// spectralTint returns the attenuation of a ray r scattered into the ray out at each wavelength of r when
+// rendering spectrally, taking over the wavelengths of out.
+func spectralTint(r *geometry.Ray, attenuation Color, out *geometry.Ray) Color {
+	if r.Wavelengths.Zero() || !out.Wavelengths.Zero() {
+		return attenuation
+	}
+	out.Wavelengths = r.Wavelengths
+	return Spectral(attenuation, r)
+}
+
+// channel returns the value of a channel of a Color, from 0 for red to 2 for blue.
+func channel(c Color, i int) float64 {
+	return [3]float64{c.Red(), c.Green(), c.Blue()}[i]
+}
+
+// rgbWavelengths holds the wavelengths standing in for the red, green and blue channels when rendering in RGB.
+var rgbWavelengths = geometry.NewVec(650, 550, 450)
+
+// airy returns the fraction of light of a wavelength in nanometres reflected by a film of a given index of
+// refraction and thickness in nanometres, lying in air over a substrate with a complex index of refraction,
+// for light arriving at an angle whose cosine is cos.
+//
+// The amplitudes reflected by both sides of the film are summed over any number of bounces within it,
+// following Airy's formula, and averaged over both polarizations.
+func airy(cos float64, lambda float64, film float64, thickness float64, substrate complex128) float64 {
+	cos1 := complex(clamp(cos, 0, 1), 0)
+	sin2 := 1 - cos1*cos1
+	n1, n2, n3 := complex(1, 0), complex(film, 0), substrate
+	cos2 := cmplx.Sqrt(1 - sin2/(n2*n2))
+	cos3 := cmplx.Sqrt(1 - sin2/(n3*n3))
+
+	phase := cmplx.Exp(complex(0, 4*math.Pi*thickness/lambda) * n2 * cos2)
+	sum := func(r12 complex128, r23 complex128) float64 {
+		return norm((r12 + r23*phase) / (1 + r12*r23*phase))
+	}
+	s := sum((n1*cos1-n2*cos2)/(n1*cos1+n2*cos2), (n2*cos2-n3*cos3)/(n2*cos2+n3*cos3))
+	p := sum((n2*cos1-n1*cos2)/(n2*cos1+n1*cos2), (n3*cos2-n2*cos3)/(n3*cos2+n2*cos3))
+	return clamp((s+p)/2, 0, 1)
+}
+
+// substrateIOR returns the index of refraction of the surface of a material at a hit, for the materials
+// that reflect light following the Fresnel equations, or false for the others.
+func substrateIOR(m Material, rec *HitRecord) (ComplexIOR, bool) {
+	dielectric := func(n float64) ComplexIOR {
+		return ComplexIOR{Eta: NewColor(n, n, n)}
+	}
+	switch mat := m.(type) {
+	case Dielectric:
+		return dielectric(mat.RefIndex), true
+	case RoughDielectric:
+		return dielectric(mat.RefIndex), true
+	case ThinDielectric:
+		return dielectric(mat.RefIndex), true
+	case Conductor:
+		return mat.IOR, true
+	case Metal:
+		return NewColoredConductor(mat.Albedo, 0).IOR, true
+	case Principled:
+		if intensity(mat.Metallic, rec) >= 0.5 && mat.BaseColor != nil {
+			return NewColoredConductor(mat.BaseColor.At(rec.u, rec.v, rec.p), 0).IOR, true
+		}
+		return dielectric(specularIndex(intensity(mat.Specular, rec))), true
+	default:
+		return ComplexIOR{}, false
+	}
+}
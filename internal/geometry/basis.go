@@ -0,0 +1,45 @@
+package geometry
+
+import "math"
+
+// Basis represents an orthonormal basis, used to move vectors between world space and a local frame
+// in which W points along a surface normal.
+type Basis struct {
+	U Unit
+	V Unit
+	W Unit
+}
+
+// NewBasis creates a new Basis around w, choosing the two other axes arbitrarily.
+//
+// This uses the branchless construction by Duff et al. which is continuous everywhere except where
+// w crosses the XY plane.
+func NewBasis(w Unit) Basis {
+	sign := math.Copysign(1, w.Z)
+	a := -1 / (sign + w.Z)
+	b := w.X * w.Y * a
+	u := NewUnit(1+sign*w.X*w.X*a, sign*b, -sign*w.X)
+	v := NewUnit(b, sign+w.Y*w.Y*a, -w.Y)
+	return Basis{U: u, V: v, W: w}
+}
+
+// NewBasisFromTangent creates a new Basis around w with U following the direction of the tangent t
+// as closely as possible. Falls back to NewBasis when t is parallel to w.
+func NewBasisFromTangent(w Unit, t Vec) Basis {
+	u := t.Sub(w.Scale(t.Dot(w.Vec)))
+	if u.LenSquared() < 1e-12 {
+		return NewBasis(w)
+	}
+	uu := u.ToUnit()
+	return Basis{U: uu, V: w.Cross(uu.Vec).ToUnit(), W: w}
+}
+
+// World converts a vector expressed in the basis to world space.
+func (b Basis) World(a Vec) Vec {
+	return b.U.Scale(a.X).Add(b.V.Scale(a.Y)).Add(b.W.Scale(a.Z))
+}
+
+// Local converts a vector expressed in world space to the basis.
+func (b Basis) Local(a Vec) Vec {
+	return Vec{X: a.Dot(b.U.Vec), Y: a.Dot(b.V.Vec), Z: a.Dot(b.W.Vec)}
+}
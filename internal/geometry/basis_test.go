@@ -0,0 +1,70 @@
+package geometry
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNewBasis(t *testing.T) {
+	tests := []struct {
+		name string
+		w    Unit
+	}{
+		{
+			name: "up",
+			w:    NewUnit(0, 0, 1),
+		},
+		{
+			name: "down",
+			w:    NewUnit(0, 0, -1),
+		},
+		{
+			name: "sideways",
+			w:    NewUnit(1, 0, 0),
+		},
+		{
+			name: "diagonal",
+			w:    NewVec(1, -2, 3).ToUnit(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBasis(tt.w)
+			for _, axis := range []Unit{b.U, b.V, b.W} {
+				if math.Abs(axis.Len()-1) > epsilon {
+					t.Errorf("NewBasis() axis %v is not a unit vector", axis)
+				}
+			}
+			if math.Abs(b.U.Dot(b.V)) > epsilon || math.Abs(b.U.Dot(b.W)) > epsilon || math.Abs(b.V.Dot(b.W)) > epsilon {
+				t.Errorf("NewBasis() = %v is not orthogonal", b)
+			}
+			if got := b.U.Cross(b.V.Vec).ToUnit(); !unitEquals(got, tt.w) {
+				t.Errorf("NewBasis() U x V = %v, want %v", got, tt.w)
+			}
+		})
+	}
+}
+
+func TestBasis_Local(t *testing.T) {
+	b := NewBasis(NewVec(1, 2, 3).ToUnit())
+	tests := []struct {
+		name string
+		v    Vec
+	}{
+		{
+			name: "along the normal",
+			v:    NewVec(1, 2, 3),
+		},
+		{
+			name: "arbitrary",
+			v:    NewVec(-4, 0.5, 2),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := b.World(b.Local(tt.v)); got.Sub(tt.v).Len() > epsilon {
+				t.Errorf("World(Local()) = %v, want %v", got, tt.v)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package geometry
+
+import (
+	"math"
+	"sort"
+)
+
+// rootEpsilon is the tolerance below which a coefficient is treated as zero when solving polynomials.
+const rootEpsilon = 1e-9
+
+// isZero returns whether x is close enough to zero to be treated as zero.
+func isZero(x float64) bool {
+	return x > -rootEpsilon && x < rootEpsilon
+}
+
+// SolveQuadratic returns the real roots of a*x^2 + b*x + c = 0 in ascending order.
+//
+// When a is zero the equation is solved as a linear equation instead.
+func SolveQuadratic(a, b, c float64) []float64 {
+	if a == 0 {
+		if b == 0 {
+			return nil
+		}
+		return []float64{-c / b}
+	}
+	discriminant := b*b - 4*a*c
+	if discriminant < 0 {
+		return nil
+	}
+	if discriminant == 0 {
+		return []float64{-b / (2 * a)}
+	}
+	// Avoid the catastrophic cancellation of the textbook formula by computing
+	// the larger root first and deriving the other from Vieta's formula.
+	q := -0.5 * (b + math.Copysign(math.Sqrt(discriminant), b))
+	x0 := q / a
+	x1 := c / q
+	if x0 > x1 {
+		x0, x1 = x1, x0
+	}
+	return []float64{x0, x1}
+}
+
+// SolveCubic returns the real roots of a*x^3 + b*x^2 + c*x + d = 0 in ascending order.
+func SolveCubic(a, b, c, d float64) []float64 {
+	if a == 0 {
+		return SolveQuadratic(b, c, d)
+	}
+	// Normal form x^3 + A*x^2 + B*x + C = 0.
+	A := b / a
+	B := c / a
+	C := d / a
+
+	// Substitute x = y - A/3 to eliminate the quadratic term: y^3 + 3*p*y + 2*q = 0.
+	sqA := A * A
+	p := (-sqA/3 + B) / 3
+	q := (2.0/27*A*sqA - A*B/3 + C) / 2
+
+	// Use Cardano's formula.
+	cbP := p * p * p
+	disc := q*q + cbP
+
+	var roots []float64
+	switch {
+	case isZero(disc):
+		if isZero(q) {
+			// One triple solution.
+			roots = []float64{0}
+		} else {
+			// One single and one double solution.
+			u := math.Cbrt(-q)
+			roots = []float64{2 * u, -u}
+		}
+	case disc < 0:
+		// Three real solutions.
+		phi := math.Acos(-q/math.Sqrt(-cbP)) / 3
+		t := 2 * math.Sqrt(-p)
+		roots = []float64{
+			t * math.Cos(phi),
+			-t * math.Cos(phi+math.Pi/3),
+			-t * math.Cos(phi-math.Pi/3),
+		}
+	default:
+		// One real solution.
+		sqrtD := math.Sqrt(disc)
+		u := math.Cbrt(sqrtD - q)
+		v := -math.Cbrt(sqrtD + q)
+		roots = []float64{u + v}
+	}
+
+	sub := A / 3
+	for i := range roots {
+		roots[i] -= sub
+	}
+	sort.Float64s(roots)
+	return roots
+}
+
+// SolveQuartic returns the real roots of a*x^4 + b*x^3 + c*x^2 + d*x + e = 0 in ascending order.
+//
+// The roots are found with Ferrari's method, which reduces the quartic to a resolvent cubic and two quadratics.
+func SolveQuartic(a, b, c, d, e float64) []float64 {
+	if a == 0 {
+		return SolveCubic(b, c, d, e)
+	}
+	// Normal form x^4 + A*x^3 + B*x^2 + C*x + D = 0.
+	A := b / a
+	B := c / a
+	C := d / a
+	D := e / a
+
+	// Substitute x = y - A/4 to eliminate the cubic term: y^4 + p*y^2 + q*y + r = 0.
+	sqA := A * A
+	p := -3.0/8*sqA + B
+	q := sqA*A/8 - A*B/2 + C
+	r := -3.0/256*sqA*sqA + sqA*B/16 - A*C/4 + D
+
+	var roots []float64
+	if isZero(r) {
+		// No absolute term: y * (y^3 + p*y + q) = 0.
+		roots = append(SolveCubic(1, 0, p, q), 0)
+	} else {
+		// Solve the resolvent cubic and take one real root.
+		z := SolveCubic(1, -p/2, -r, r*p/2-q*q/8)[0]
+
+		// Use it to build two quadratic equations.
+		u := z*z - r
+		v := 2*z - p
+		switch {
+		case isZero(u):
+			u = 0
+		case u > 0:
+			u = math.Sqrt(u)
+		default:
+			return nil
+		}
+		switch {
+		case isZero(v):
+			v = 0
+		case v > 0:
+			v = math.Sqrt(v)
+		default:
+			return nil
+		}
+		if q < 0 {
+			v = -v
+		}
+		roots = append(SolveQuadratic(1, v, z-u), SolveQuadratic(1, -v, z+u)...)
+	}
+
+	sub := A / 4
+	for i := range roots {
+		roots[i] -= sub
+	}
+	sort.Float64s(roots)
+	return roots
+}
@@ -0,0 +1,147 @@
+package geometry
+
+import (
+	"math"
+	"testing"
+)
+
+const rootTolerance = 0.000001
+
+func rootsEqual(got []float64, want []float64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if math.Abs(got[i]-want[i]) > rootTolerance {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSolveQuadratic(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b, c float64
+		want    []float64
+	}{
+		{
+			name: "two roots",
+			a:    1, b: -3, c: 2,
+			want: []float64{1, 2},
+		},
+		{
+			name: "double root",
+			a:    1, b: -2, c: 1,
+			want: []float64{1},
+		},
+		{
+			name: "no real roots",
+			a:    1, b: 0, c: 1,
+			want: nil,
+		},
+		{
+			name: "linear",
+			a:    0, b: 2, c: -4,
+			want: []float64{2},
+		},
+		{
+			name: "large coefficient",
+			a:    1, b: -1e8, c: 1,
+			want: []float64{1e-8, 1e8},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SolveQuadratic(tt.a, tt.b, tt.c); !rootsEqual(got, tt.want) {
+				t.Errorf("SolveQuadratic() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSolveCubic(t *testing.T) {
+	tests := []struct {
+		name       string
+		a, b, c, d float64
+		want       []float64
+	}{
+		{
+			name: "three roots",
+			// (x - 1)(x - 2)(x - 3)
+			a: 1, b: -6, c: 11, d: -6,
+			want: []float64{1, 2, 3},
+		},
+		{
+			name: "one root",
+			// (x - 1)(x^2 + 1)
+			a: 1, b: -1, c: 1, d: -1,
+			want: []float64{1},
+		},
+		{
+			name: "triple root",
+			// (x - 2)^3
+			a: 1, b: -6, c: 12, d: -8,
+			want: []float64{2},
+		},
+		{
+			name: "scaled",
+			// 2x(x + 1)(x - 1)
+			a: 2, b: 0, c: -2, d: 0,
+			want: []float64{-1, 0, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SolveCubic(tt.a, tt.b, tt.c, tt.d); !rootsEqual(got, tt.want) {
+				t.Errorf("SolveCubic() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSolveQuartic(t *testing.T) {
+	tests := []struct {
+		name          string
+		a, b, c, d, e float64
+		want          []float64
+	}{
+		{
+			name: "four roots",
+			// (x - 1)(x - 2)(x - 3)(x - 4)
+			a: 1, b: -10, c: 35, d: -50, e: 24,
+			want: []float64{1, 2, 3, 4},
+		},
+		{
+			name: "two roots",
+			// (x^2 - 4)(x^2 + 1)
+			a: 1, b: 0, c: -3, d: 0, e: -4,
+			want: []float64{-2, 2},
+		},
+		{
+			name: "no real roots",
+			// (x^2 + 1)(x^2 + 4)
+			a: 1, b: 0, c: 5, d: 0, e: 4,
+			want: nil,
+		},
+		{
+			name: "zero root",
+			// x(x - 1)(x + 1)(x - 2)
+			a: 1, b: -2, c: -1, d: 2, e: 0,
+			want: []float64{-1, 0, 1, 2},
+		},
+		{
+			name: "torus",
+			// A ray along the X axis through a torus with major radius 2 and minor radius 0.5.
+			a: 1, b: 0, c: -8.5, d: 0, e: 14.0625,
+			want: []float64{-2.5, -1.5, 1.5, 2.5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SolveQuartic(tt.a, tt.b, tt.c, tt.d, tt.e); !rootsEqual(got, tt.want) {
+				t.Errorf("SolveQuartic() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
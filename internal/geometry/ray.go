@@ -6,6 +6,12 @@ type Ray struct {
 	Direction Unit
 	Time      float64
 	Rnd       Rnd
+	// Wavelengths holds the three wavelengths in nanometres carried by a ray when rendering spectrally,
+	// or is zero when rendering in RGB. A wavelength of zero has been dropped from the ray.
+	Wavelengths Vec
+	// Spread is set on rays scattered in a direction picked from a continuous spread of directions, such as off
+	// a rough surface, rather than from a few possible ones, such as off a mirror.
+	Spread bool
 }
 
 // NewRay creates a new ray with an origin and direction.
@@ -212,3 +212,15 @@ func (v Vec) Max(v2 Vec) Vec {
 func RandUnit(rnd Rnd) Unit {
 	return NewVec(2*rnd.Float64()-1, 2*rnd.Float64()-1, 2*rnd.Float64()-1).ToUnit()
 }
+
+// RandCosineHemisphere returns a random unit vector in the hemisphere around the Z axis, with a density
+// proportional to the cosine of its angle to the axis.
+//
+// This works by picking a point uniformly on the unit disk and projecting it up onto the hemisphere,
+// following Malley's method.
+func RandCosineHemisphere(rnd Rnd) Vec {
+	r := math.Sqrt(rnd.Float64())
+	phi := 2 * math.Pi * rnd.Float64()
+	x, y := r*math.Cos(phi), r*math.Sin(phi)
+	return NewVec(x, y, math.Sqrt(math.Max(0, 1-x*x-y*y)))
+}
@@ -2,6 +2,7 @@ package geometry
 
 import (
 	"fmt"
+	"math"
 	"reflect"
 	"testing"
 )
@@ -55,6 +56,44 @@ func TestRandUnit(t *testing.T) {
 	}
 }
 
+func TestRandCosineHemisphere(t *testing.T) {
+	tests := []struct {
+		name string
+		rnd  RndMock
+		want Unit
+	}{
+		{
+			name: "centre of the disk",
+			rnd: RndMock{
+				floats: []float64{0, 0.3},
+			},
+			want: NewUnit(0, 0, 1),
+		},
+		{
+			name: "edge of the disk",
+			rnd: RndMock{
+				floats: []float64{1, 0.25},
+			},
+			want: NewUnit(0, 1, 0),
+		},
+		{
+			name: "halfway",
+			rnd: RndMock{
+				floats: []float64{0.25, 0.5},
+			},
+			want: NewUnit(-0.5, 0, math.Sqrt(0.75)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RandCosineHemisphere(&tt.rnd)
+			if !unitEquals(Unit{got}, tt.want) {
+				t.Errorf("RandCosineHemisphere() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestVec_Scale(t *testing.T) {
 	type fields struct {
 		X float64